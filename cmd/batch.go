@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Erl-koenig/switchdl/internal/media"
+	"github.com/spf13/cobra"
+)
+
+const batchFileFlag = "batch-file"
+
+// argsOrBatchFile requires IDs as arguments unless --batch-file is given.
+func argsOrBatchFile(cmd *cobra.Command, args []string) error {
+	if batchFile, _ := cmd.Flags().GetString(batchFileFlag); batchFile != "" {
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// readBatchFile returns the valid entries of the --batch-file, if any, with
+// their IDs extracted by parseID. Invalid lines are printed and added to
+// summary as failures.
+func readBatchFile(
+	cmd *cobra.Command,
+	client *media.Client,
+	summary *media.DownloadSummary,
+	parseID func(string) (string, error),
+) ([]media.BatchEntry, error) {
+	batchFile, _ := cmd.Flags().GetString(batchFileFlag)
+	if batchFile == "" {
+		return nil, nil
+	}
+
+	entries, err := media.ReadBatchFile(batchFile)
+	if err != nil {
+		return nil, err
+	}
+	valid := make([]media.BatchEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Err == nil {
+			if id, err := parseID(entry.ID); err != nil {
+				entry.Err = fmt.Errorf("line %d: %w", entry.Line, err)
+			} else {
+				entry.ID = id
+			}
+		}
+		if entry.Err != nil {
+			fmt.Fprintf(client.Out, "Skipping invalid batch file entry: %v\n", entry.Err)
+			summary.AddFailed(media.DownloadResult{VideoID: entry.ID, Error: entry.Err})
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("batch file contains no IDs")
+	}
+	return valid, nil
+}
+
+func addBatchFileFlag(cmd *cobra.Command, kind string) {
+	cmd.Flags().String(batchFileFlag, "",
+		fmt.Sprintf("File with one %s ID or URL per line (- for stdin), see README for overrides", kind))
+}
@@ -1,34 +1,89 @@
 package cmd
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/Erl-koenig/switchdl/internal/media"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var channelCmd = &cobra.Command{
-	Use:   "channel <id>",
+	Use:   "channel <id|url>",
 	Short: "Download videos from one or multiple channels",
-	Long: `Download videos from one or more SwitchTube channels by providing their unique channel IDs.
+	Long: `Download videos from one or more SwitchTube channels by providing their unique channel IDs or URLs.
 You can either download all videos at once or select which ones specifically.`,
 	Example: ` switchdl channel abcdef1234
- switchdl channel abcdef1234 ghijk56789 -a`,
-	Args: cobra.MinimumNArgs(1),
+ switchdl channel abcdef1234 ghijk56789 -a
+ switchdl channel --batch-file channels.txt -a`,
+	Args: argsOrBatchFile,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		client := media.NewClient(downloadCfg.AccessToken)
+		client := newClient(downloadCfg.AccessToken)
 		downloadCfg.All = viper.GetBool("all")
-		for _, channelID := range args {
-			downloadCfg.ChannelID = channelID
-			if err := client.DownloadChannel(cmd.Context(), &downloadCfg); err != nil {
-				return err // Return on the first channel that fails
-			}
+		summary := &media.DownloadSummary{Results: []media.DownloadResult{}}
+		defer client.Events.WriteSummary(summary)
+
+		channelIDs, err := parseIDs(args, client.ParseChannelID)
+		if err != nil {
+			return err
+		}
+		batchEntries, err := readBatchFile(cmd, client, summary, client.ParseChannelID)
+		if err != nil {
+			return err
 		}
-		return nil
+		entries := make([]media.BatchEntry, 0, len(channelIDs)+len(batchEntries))
+		for _, channelID := range channelIDs {
+			entries = append(entries, media.BatchEntry{ID: channelID})
+		}
+		entries = append(entries, batchEntries...)
+
+		return downloadChannels(cmd, client, entries, summary)
 	},
 }
 
+// downloadChannels downloads the channels one after another and adds their
+// results to summary. A failing channel doesn't stop the remaining ones.
+func downloadChannels(
+	cmd *cobra.Command,
+	client *media.Client,
+	entries []media.BatchEntry,
+	summary *media.DownloadSummary,
+) error {
+	var failed []media.DownloadResult
+	for _, entry := range entries {
+		channelCfg := downloadCfg
+		channelCfg.ChannelID = entry.ID
+		channelCfg.OutputDir = filepath.Join(downloadCfg.OutputDir, entry.Subdir)
+
+		var err error
+		if entry.Filename != "" {
+			err = fmt.Errorf("line %d: filename can only be set for videos", entry.Line)
+		} else {
+			var channelSummary *media.DownloadSummary
+			channelSummary, err = client.DownloadChannel(cmd.Context(), &channelCfg)
+			if err == nil {
+				summary.Merge(channelSummary)
+				continue
+			}
+		}
+		if cmd.Context().Err() != nil {
+			return err
+		}
+		fmt.Fprintf(client.Out, "Failed to download channel %s: %v\n", entry.ID, err)
+		failed = append(failed, media.DownloadResult{ChannelID: entry.ID, Error: err})
+		summary.AddFailed(failed[len(failed)-1])
+	}
+
+	if len(failed) > 0 {
+		return downloadFailures(fmt.Sprintf("failed to download %d of %d channel(s)", len(failed), len(entries)), failed)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(channelCmd)
 	channelCmd.Flags().BoolP("all", "a", false, "Download all videos without prompting")
 	cobra.CheckErr(viper.BindPFlag("all", channelCmd.Flags().Lookup("all")))
+	addBatchFileFlag(channelCmd, "channel")
 }
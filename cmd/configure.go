@@ -2,25 +2,41 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/Erl-koenig/switchdl/internal/keyringconfig"
-	"github.com/Erl-koenig/switchdl/internal/media"
 	"github.com/spf13/cobra"
-	"github.com/zalando/go-keyring"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var configureCmd = &cobra.Command{
 	Use:   "configure",
 	Short: "Manage your SwitchTube access token",
 	Long: `The configure command allows you to set, show, validate, or delete your SwitchTube access token.
+All commands work on the profile selected with --profile, "default" if not given.
+Tokens are stored in the OS keyring or, where it isn't available, in a file (see --token-store).
 
 To set or update your token:
   switchdl configure
 
+To set the token without prompting, e.g. in provisioning scripts:
+  switchdl configure --stdin < token.txt
+
+To set the token of another profile:
+  switchdl configure --profile teaching
+
+To list all profiles:
+  switchdl configure list
+
 To check if a token is currently stored:
   switchdl configure show
 
@@ -31,32 +47,56 @@ To delete the stored token:
   switchdl configure delete`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("Enter your SwitchTube access token: ")
-		token, err := reader.ReadString('\n')
+		fromStdin, _ := cmd.Flags().GetBool("stdin")
+		token, err := readToken(fromStdin)
 		if err != nil {
 			return fmt.Errorf("failed to read token: %w", err)
 		}
-		token = strings.TrimSpace(token)
-		if err = keyringconfig.SetAccessToken(token); err != nil {
+		if err = keyringconfig.SetAccessToken(tokenStore, downloadCfg.Profile, token); err != nil {
 			return err
 		}
-		fmt.Println("Access token successfully saved.")
+		fmt.Printf("Access token%s successfully saved to %s.\n", forProfile(downloadCfg.Profile), tokenStore)
+		if err = tokenUsageLog().RecordStored(token, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 
 		return nil
 	},
 }
 
+// readToken reads the token from stdin, all of it with --stdin, otherwise
+// after a prompt. The token isn't echoed if stdin is a terminal.
+func readToken(fromStdin bool) (string, error) {
+	if fromStdin {
+		data, err := io.ReadAll(os.Stdin)
+		return strings.TrimSpace(string(data)), err
+	}
+
+	fmt.Printf("Enter your SwitchTube access token%s: ", forProfile(downloadCfg.Profile))
+	fd := int(os.Stdin.Fd()) //nolint:gosec // file descriptors fit into int
+	if term.IsTerminal(fd) {
+		token, err := term.ReadPassword(fd)
+		fmt.Println() // the newline wasn't echoed either
+		return strings.TrimSpace(string(token)), err
+	}
+	token, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if errors.Is(err, io.EOF) && token != "" { // last line without newline
+		err = nil
+	}
+	return strings.TrimSpace(token), err
+}
+
 var showCmd = &cobra.Command{
 	Use:   "show",
-	Short: "Check if an access token is currently stored",
+	Short: "Check if an access token is currently stored and when it expires",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := keyringconfig.GetAccessToken("")
+		token, err := keyringconfig.GetAccessToken(tokenStore, downloadCfg.Profile, keyringconfig.TokenSources{})
 		if err == nil {
-			fmt.Println("An access token is currently stored.")
-		} else if errors.Is(err, keyring.ErrNotFound) {
-			fmt.Println("No access token is currently stored.")
+			fmt.Printf("An access token%s is currently stored in %s.\n", forProfile(downloadCfg.Profile), tokenStore)
+			return printTokenUsage(token)
+		} else if errors.Is(err, keyringconfig.ErrTokenNotFound) {
+			fmt.Printf("No access token%s is currently stored in %s.\n", forProfile(downloadCfg.Profile), tokenStore)
 		} else {
 			return fmt.Errorf("failed to check token status: %w", err)
 		}
@@ -69,21 +109,25 @@ var validateCmd = &cobra.Command{
 	Short: "Validate the stored access token with the SwitchTube API",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		token, err := keyringconfig.GetAccessToken("")
+		token, err := keyringconfig.GetAccessToken(tokenStore, downloadCfg.Profile, tokenSources())
 		if err != nil {
 			return err
 		}
+		downloadCfg.AccessToken = token // for the hint if it is rejected
 
-		client := media.NewClient(token)
-		if err = client.ValidateToken(cmd.Context()); err != nil {
-			fmt.Println(err)
-			if strings.Contains(err.Error(), "invalid or expired") {
-				fmt.Println("Please run 'switchdl configure' to update it.")
+		client := newClient(token)
+		validationErr := client.ValidateToken(cmd.Context())
+		if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput || downloadCfg.Output == outputJSON {
+			if err = writeValidationResult(token, validationErr); err != nil {
+				return err
+			}
+		} else if validationErr == nil {
+			fmt.Println("Access token is valid.")
+			if err = printTokenUsage(token); err != nil {
+				return err
 			}
-			return nil
 		}
-		fmt.Println("Access token is valid.")
-		return nil
+		return validationErr
 	},
 }
 
@@ -92,17 +136,132 @@ var deleteCmd = &cobra.Command{
 	Short: "Delete the stored access token",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := keyringconfig.DeleteAccessToken(); err != nil {
+		if err := keyringconfig.DeleteAccessToken(tokenStore, downloadCfg.Profile); err != nil {
 			return err
 		}
-		fmt.Println("Access token successfully deleted or was not found.")
+		fmt.Printf("Access token%s successfully deleted from %s or was not found.\n", forProfile(downloadCfg.Profile), tokenStore)
 		return nil
 	},
 }
 
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles with their token status and settings",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := tokenStore.Profiles()
+		if err != nil {
+			return err
+		}
+		for name := range viper.GetStringMap("profiles") {
+			names = append(names, name)
+		}
+		names = append(names, keyringconfig.DefaultProfile, downloadCfg.Profile)
+		slices.Sort(names)
+		names = slices.Compact(names)
+
+		profiles := make([]profileInfo, 0, len(names))
+		for _, name := range names {
+			_, err = keyringconfig.GetAccessToken(tokenStore, name, keyringconfig.TokenSources{})
+			if err != nil && !errors.Is(err, keyringconfig.ErrTokenNotFound) {
+				return err
+			}
+			profiles = append(profiles, profileInfo{
+				Name:        name,
+				Active:      name == downloadCfg.Profile,
+				TokenStored: err == nil,
+				OutputDir:   viper.GetString("profiles." + name + ".output-dir"),
+				BaseURL:     viper.GetString("profiles." + name + ".base-url"),
+			})
+		}
+
+		if downloadCfg.Output == outputJSON {
+			for _, profile := range profiles {
+				if err = writeStructured(profile); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		fmt.Printf("Tokens are stored in %s.\n\n", tokenStore)
+		return printProfiles(profiles)
+	},
+}
+
+// profileInfo is a row of configure list.
+type profileInfo struct {
+	Name        string `json:"name"`
+	Active      bool   `json:"active"` // Selected by --profile or the config file
+	TokenStored bool   `json:"token_stored"`
+	OutputDir   string `json:"output_dir,omitempty"`
+	BaseURL     string `json:"base_url,omitempty"`
+}
+
+func printProfiles(profiles []profileInfo) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // column padding
+	fmt.Fprintln(writer, "PROFILE\tTOKEN\tOUTPUT DIR\tBASE URL")
+	for _, profile := range profiles {
+		name := profile.Name
+		if profile.Active {
+			name += " *"
+		}
+		token := "not stored"
+		if profile.TokenStored {
+			token = "stored"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n",
+			name, token, orDash(profile.OutputDir), orDash(profile.BaseURL))
+	}
+	return writer.Flush()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// forProfile names the profile in messages, unless it is the default one.
+func forProfile(profile string) string {
+	if profile == keyringconfig.DefaultProfile {
+		return ""
+	}
+	return fmt.Sprintf(" of profile %q", profile)
+}
+
+// validationResult is the output of configure validate in JSON mode.
+type validationResult struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// Known if the token was used before, the expiry is estimated from the last use
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastUsed  time.Time `json:"last_used,omitzero"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+func writeValidationResult(token string, validationErr error) error {
+	result := validationResult{Valid: validationErr == nil}
+	if validationErr != nil {
+		result.Error = validationErr.Error()
+	}
+	if usage, err := tokenUsageLog().Get(token); err == nil {
+		result.FirstSeen = usage.FirstSeen
+		result.LastUsed = usage.LastUsed
+		result.ExpiresAt = usage.ExpiresAt()
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		return fmt.Errorf("failed to write validation result: %w", err)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(configureCmd)
+	configureCmd.Flags().Bool("stdin", false, "Read the token from stdin without prompting")
 	configureCmd.AddCommand(showCmd)
+	configureCmd.AddCommand(listCmd)
 	configureCmd.AddCommand(validateCmd)
+	validateCmd.Flags().Bool("json", false, "Print the result as JSON, same as --output json")
 	configureCmd.AddCommand(deleteCmd)
 }
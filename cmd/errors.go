@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Erl-koenig/switchdl/internal/keyringconfig"
+	"github.com/Erl-koenig/switchdl/internal/media"
+)
+
+// Exit codes, so that wrapper scripts can tell the cause of a failure.
+const (
+	exitFailure      = 1 // Any other error
+	exitUnauthorized = 2 // The access token is invalid or expired
+	exitNotFound     = 3 // A video or channel doesn't exist or isn't accessible
+	exitRateLimited  = 4 // The server rejected requests as too many
+	exitNetwork      = 5 // The server couldn't be reached
+	exitInterrupted  = 130
+)
+
+// failuresError sums up several failures in one message. errors.Is and
+// errors.As still see all of them, so they determine the exit code.
+type failuresError struct {
+	msg  string
+	errs []error
+}
+
+func (e *failuresError) Error() string {
+	return e.msg
+}
+
+func (e *failuresError) Unwrap() []error {
+	return e.errs
+}
+
+// downloadFailures returns an error with msg wrapping the failed results.
+func downloadFailures(msg string, results []media.DownloadResult) error {
+	errs := make([]error, 0, len(results))
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, result.Error)
+		}
+	}
+	return &failuresError{msg: msg, errs: errs}
+}
+
+// exitCode maps an error to the exit code of the process. If several causes
+// are wrapped, the most actionable one wins.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, media.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, media.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, media.ErrNotFound):
+		return exitNotFound
+	case media.IsNetworkError(err):
+		return exitNetwork
+	default:
+		return exitFailure
+	}
+}
+
+// printHint tells the user how to fix common failures.
+func printHint(code int) {
+	switch code {
+	case exitUnauthorized:
+		fmt.Fprintf(os.Stderr, "Run '%s' to update the access token.\n", keyringconfig.ConfigureCommand(downloadCfg.Profile))
+		printLastUse(downloadCfg.AccessToken)
+	case exitNotFound:
+		fmt.Fprintln(os.Stderr, "Check the video or channel ID, or whether your account can access it.")
+	case exitRateLimited:
+		fmt.Fprintln(os.Stderr, "Try again later, or with fewer --jobs and --segments.")
+	case exitNetwork:
+		fmt.Fprintln(os.Stderr, "Check your network connection.")
+	}
+}
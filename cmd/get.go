@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Erl-koenig/switchdl/internal/media"
+	"github.com/spf13/cobra"
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get <url>",
+	Short: "Download videos and channels by their SwitchTube URLs",
+	Long: `Download everything behind one or more SwitchTube URLs, e.g. copied from the browser.
+Video pages and embed links are downloaded like with the video command, channel pages like with the channel command.`,
+	Example: `  switchdl get https://tube.switch.ch/videos/1234567890
+  switchdl get https://tube.switch.ch/channels/abcdef1234 https://tube.switch.ch/embed/9876543210 -a`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newClient(downloadCfg.AccessToken)
+		var videoIDs []string
+		var channels []media.BatchEntry
+		for _, arg := range args {
+			resource, err := client.ParseURL(arg)
+			if err != nil {
+				return fmt.Errorf("%w (get needs URLs to tell videos and channels apart)", err)
+			}
+			switch resource.Kind {
+			case media.ResourceVideo:
+				if !slices.Contains(videoIDs, resource.ID) {
+					videoIDs = append(videoIDs, resource.ID)
+				}
+			case media.ResourceChannel:
+				channels = append(channels, media.BatchEntry{ID: resource.ID})
+			}
+		}
+		if cmd.Flags().Changed("all") {
+			downloadCfg.All, _ = cmd.Flags().GetBool("all")
+		}
+
+		summary := &media.DownloadSummary{Results: []media.DownloadResult{}}
+		defer client.Events.WriteSummary(summary)
+
+		var videoErr error
+		if len(videoIDs) > 0 {
+			videoCfg := downloadCfg
+			videoCfg.VideoIDs = videoIDs
+			videoCfg.Filename = ""
+			videoSummary := client.DownloadVideos(cmd.Context(), &videoCfg)
+			summary.Merge(videoSummary)
+			if videoSummary.Succeeded == 0 {
+				videoErr = downloadFailures("failed to download any videos", videoSummary.Results)
+			}
+		}
+		return errors.Join(videoErr, downloadChannels(cmd, client, channels, summary))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	getCmd.Flags().BoolP("all", "a", false, "Download all videos of channels without prompting")
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show metadata of videos and channels without downloading",
+	Long: `Show the metadata returned by the SwitchTube API without downloading anything.
+Use --output json or --output yaml for machine-readable output.`,
+	Example: `  switchdl info video 1234567890
+  switchdl info channel abcdef1234 --output yaml`,
+	Args: cobra.NoArgs,
+}
+
+var infoVideoCmd = &cobra.Command{
+	Use:   "video <id|url>",
+	Short: "Show title, published date, duration and variants of videos",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newClient(downloadCfg.AccessToken)
+		videoIDs, err := parseIDs(args, client.ParseVideoID)
+		if err != nil {
+			return err
+		}
+		for i, videoID := range videoIDs {
+			info, err := client.FetchVideoInfo(cmd.Context(), videoID)
+			if err != nil {
+				return err
+			}
+			if downloadCfg.Output != outputText {
+				if err = writeStructured(info); err != nil {
+					return err
+				}
+				continue
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			if err = client.PrintVideoInfo(info); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var infoChannelCmd = &cobra.Command{
+	Use:   "channel <id|url>",
+	Short: "Show the details and all videos of channels",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newClient(downloadCfg.AccessToken)
+		channelIDs, err := parseIDs(args, client.ParseChannelID)
+		if err != nil {
+			return err
+		}
+		for i, channelID := range channelIDs {
+			info, err := client.FetchChannelInfo(cmd.Context(), channelID)
+			if err != nil {
+				return err
+			}
+			if downloadCfg.Output != outputText {
+				if err = writeStructured(info); err != nil {
+					return err
+				}
+				continue
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			if err = client.PrintChannelInfo(info); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// writeStructured prints v as one JSON line or as a YAML document. The YAML
+// is converted from the JSON encoding, so both use the same field names.
+func writeStructured(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if downloadCfg.Output == outputJSON {
+		_, err = fmt.Printf("%s\n", data)
+		return err
+	}
+
+	var node yaml.Node
+	if err = yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to convert output to YAML: %w", err)
+	}
+	resetStyle(&node)
+	if _, err = fmt.Println("---"); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2) //nolint:mnd // common YAML indentation
+	if err = enc.Encode(&node); err != nil {
+		return fmt.Errorf("failed to write YAML: %w", err)
+	}
+	return enc.Close()
+}
+
+// resetStyle drops the JSON flow style and quoting, so the YAML is written in block style.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.AddCommand(infoVideoCmd)
+	infoCmd.AddCommand(infoChannelCmd)
+}
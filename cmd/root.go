@@ -2,11 +2,18 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/Erl-koenig/switchdl/internal/keyringconfig"
 	"github.com/Erl-koenig/switchdl/internal/media"
@@ -16,6 +23,13 @@ import (
 
 const configName = "config"
 
+// Values of --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml" // info only
+)
+
 var downloadCfg media.DownloadConfig
 
 var rootCmd = &cobra.Command{
@@ -26,28 +40,167 @@ var rootCmd = &cobra.Command{
 			return nil
 		}
 
+		// Arguments are valid at this point, so errors from here on are not usage errors
+		cmd.SilenceUsage = true
+
+		if err := applyProfileSettings(cmd, viper.GetString("profile")); err != nil {
+			return err
+		}
+
 		if err := viper.Unmarshal(&downloadCfg); err != nil {
 			return fmt.Errorf("failed to unmarshal config: %w", err)
 		}
 
+		ignoreUntrustedSettings(cmd)
+
 		if downloadCfg.Overwrite && downloadCfg.Skip {
 			return errors.New("cannot use --overwrite (-w) and --skip (-s) flags together")
 		}
 
-		token, err := keyringconfig.GetAccessToken(downloadCfg.AccessToken)
+		if downloadCfg.Jobs < 1 {
+			return errors.New("--jobs (-j) must be at least 1")
+		}
+
+		if downloadCfg.Segments < 1 {
+			return errors.New("--segments must be at least 1")
+		}
+
+		if downloadCfg.DeleteVideo && !downloadCfg.ExtractAudio {
+			return errors.New("--delete-video requires --extract-audio")
+		}
+
+		if downloadCfg.Retries < 0 || downloadCfg.RetryDelay < 0 {
+			return errors.New("--retries and --retry-delay cannot be negative")
+		}
+
+		switch downloadCfg.Output {
+		case outputText, outputJSON:
+		case outputYAML:
+			if cmd.Parent() != infoCmd {
+				return fmt.Errorf("--output %q is only supported by the info command", outputYAML)
+			}
+		default:
+			return fmt.Errorf("invalid --output %q, must be %q or %q", downloadCfg.Output, outputText, outputJSON)
+		}
+
+		if err := media.ValidateOutputTemplate(downloadCfg.OutputTemplate); err != nil {
+			return err
+		}
+
+		baseURL, err := media.ParseBaseURL(downloadCfg.BaseURL)
+		if err != nil {
+			return err
+		}
+		downloadCfg.BaseURL = baseURL
+
+		selector, err := media.ParseFormat(downloadCfg.Format)
+		if err != nil {
+			return err
+		}
+		downloadCfg.Selector = selector
+
+		if downloadCfg.ArchiveFile != "" {
+			archive, err := media.OpenDownloadArchive(downloadCfg.ArchiveFile)
+			if err != nil {
+				return err
+			}
+			downloadCfg.Archive = archive
+		}
+
+		if cmd == verifyCmd { // works offline on local files
+			return nil
+		}
+
+		if cmd == configureCmd || cmd.Parent() == configureCmd { // manages the token itself
+			return openTokenStore()
+		}
+
+		// The store is only needed without another token source
+		if tokenSources().IsZero() {
+			if err = openTokenStore(); err != nil {
+				return err
+			}
+		}
+		token, err := keyringconfig.GetAccessToken(tokenStore, downloadCfg.Profile, tokenSources())
 		if err != nil {
 			return err
 		}
 		downloadCfg.AccessToken = token
+		warnTokenExpiry(token)
 
 		return os.MkdirAll(downloadCfg.OutputDir, media.DefaultDirectoryPermissions)
 	},
 }
 
+// newClient creates an API client configured by the global flags and config file.
+func newClient(token string) *media.Client {
+	client := media.NewClient(token)
+	client.Retry.MaxAttempts = downloadCfg.Retries + 1
+	client.Retry.BaseDelay = downloadCfg.RetryDelay
+	var recordOnce sync.Once // a run only needs to be recorded once
+	client.TokenUsed = func() {
+		recordOnce.Do(func() { recordTokenUse(client, token) })
+	}
+	if downloadCfg.BaseURL != "" {
+		client.BaseURL = downloadCfg.BaseURL
+	}
+	if downloadCfg.Output == outputJSON {
+		client.Out = os.Stderr
+		client.Events = media.NewEventWriter(os.Stdout)
+	}
+	if downloadCfg.Verbose {
+		client.Logger = slog.New(
+			slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	}
+	return client
+}
+
+// applyProfileSettings lets the settings of the profile in the config file
+// override the top-level ones. Flags given on the command line still win.
+func applyProfileSettings(cmd *cobra.Command, profile string) error {
+	if err := keyringconfig.ValidateProfile(profile); err != nil {
+		return err
+	}
+	for key, value := range viper.GetStringMap("profiles." + profile) {
+		if key == "profile" || key == "profiles" {
+			return fmt.Errorf("profile %q cannot set %q", profile, key)
+		}
+		if flag := cmd.Flags().Lookup(key); flag != nil && flag.Changed {
+			continue
+		}
+		viper.Set(key, value)
+	}
+	return nil
+}
+
+// parseIDs extracts the IDs of arguments given as ID or URL.
+func parseIDs(args []string, parseID func(string) (string, error)) ([]string, error) {
+	ids := make([]string, len(args))
+	for i, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
 func Execute() {
-	err := rootCmd.Execute()
+	// Ctrl-C cancels running downloads, which keeps their .part files for resuming
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop() // a second Ctrl-C terminates immediately
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
-		os.Exit(1)
+		code := exitCode(err)
+		printHint(code)
+		os.Exit(code)
 	}
 }
 
@@ -62,7 +215,45 @@ func init() {
 	rootCmd.PersistentFlags().
 		BoolVarP(&downloadCfg.SelectVariant, "select-variant", "v", false, "List all video variants (quality) and prompt for selection")
 	rootCmd.PersistentFlags().
-		String("token", "", "Access token for API authentication (overrides configured token)")
+		StringVar(&downloadCfg.Format, "format", "", "Variant preference, e.g. \"720p/best\", \"worst\" or \"name~=1080\" (see README)")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.OutputTemplate, "output-template", "", "Output path template, e.g. \"{channel}/{index:03} - {title}\" (see README for placeholders)")
+	rootCmd.PersistentFlags().
+		IntVarP(&downloadCfg.Jobs, "jobs", "j", 1, "Number of videos to download in parallel")
+	rootCmd.PersistentFlags().
+		IntVar(&downloadCfg.Segments, "segments", 1, "Number of parallel connections per video (requires server support for ranges)")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.ArchiveFile, "download-archive", "", "File recording downloaded video IDs, videos listed in it are skipped")
+	rootCmd.PersistentFlags().
+		IntVar(&downloadCfg.Retries, "retries", media.DefaultRetries, "Number of retries for transient network and server errors")
+	rootCmd.PersistentFlags().
+		DurationVar(&downloadCfg.RetryDelay, "retry-delay", media.DefaultRetryDelay, "Initial delay between retries, doubled on every retry")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.WriteInfoJSON, "write-info-json", false, "Write the video metadata to <name>.info.json next to each video")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.WriteNFO, "write-nfo", false, "Write a Kodi/Jellyfin <name>.nfo file next to each video")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.EmbedMetadata, "embed-metadata", false, "Embed title, channel, date and source URL into the MP4 file")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.ExtractAudio, "extract-audio", false, "Also save the audio track of each video as <name>.m4a")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.DeleteVideo, "delete-video", false, "Delete the video after extracting its audio (with --extract-audio)")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.NoVerify, "no-verify", false, "Skip the MP4 integrity and duration check of downloaded files")
+	rootCmd.PersistentFlags().
+		BoolVar(&downloadCfg.Verbose, "verbose", false, "Print diagnostic logs (requests, retries) to stderr")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.Output, "output", outputText, "Output format: text, or json for JSON lines on stdout and messages on stderr (info also supports yaml)")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.BaseURL, "base-url", media.SwitchTubeBaseURL, "URL of the SwitchTube instance, e.g. of a staging instance or mirror")
+	rootCmd.PersistentFlags().
+		String("profile", keyringconfig.DefaultProfile, "Named profile selecting the stored access token and profile settings of the config file")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.TokenStore, "token-store", keyringconfig.BackendAuto, "Where tokens are stored: auto, keyring, encrypted-file or file (see README)")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.AccessToken, "token", "", "Access token for API authentication (overrides configured token)")
+	rootCmd.PersistentFlags().
+		StringVar(&downloadCfg.TokenFile, "token-file", "", "File containing the access token, e.g. a Docker or Kubernetes secret")
 
 	cobra.CheckErr(viper.BindPFlag("output-dir", rootCmd.PersistentFlags().Lookup("output-dir")))
 	cobra.CheckErr(viper.BindPFlag("skip", rootCmd.PersistentFlags().Lookup("skip")))
@@ -70,14 +261,92 @@ func init() {
 	cobra.CheckErr(
 		viper.BindPFlag("select-variant", rootCmd.PersistentFlags().Lookup("select-variant")),
 	)
+	cobra.CheckErr(viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format")))
+	cobra.CheckErr(
+		viper.BindPFlag("output-template", rootCmd.PersistentFlags().Lookup("output-template")),
+	)
+	cobra.CheckErr(viper.BindPFlag("jobs", rootCmd.PersistentFlags().Lookup("jobs")))
+	cobra.CheckErr(viper.BindPFlag("segments", rootCmd.PersistentFlags().Lookup("segments")))
+	cobra.CheckErr(
+		viper.BindPFlag("download-archive", rootCmd.PersistentFlags().Lookup("download-archive")),
+	)
+	cobra.CheckErr(viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries")))
+	cobra.CheckErr(viper.BindPFlag("retry-delay", rootCmd.PersistentFlags().Lookup("retry-delay")))
+	cobra.CheckErr(
+		viper.BindPFlag("write-info-json", rootCmd.PersistentFlags().Lookup("write-info-json")),
+	)
+	cobra.CheckErr(viper.BindPFlag("write-nfo", rootCmd.PersistentFlags().Lookup("write-nfo")))
+	cobra.CheckErr(
+		viper.BindPFlag("embed-metadata", rootCmd.PersistentFlags().Lookup("embed-metadata")),
+	)
+	cobra.CheckErr(
+		viper.BindPFlag("extract-audio", rootCmd.PersistentFlags().Lookup("extract-audio")),
+	)
+	cobra.CheckErr(viper.BindPFlag("delete-video", rootCmd.PersistentFlags().Lookup("delete-video")))
+	cobra.CheckErr(viper.BindPFlag("no-verify", rootCmd.PersistentFlags().Lookup("no-verify")))
+	cobra.CheckErr(viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose")))
+	cobra.CheckErr(viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("base-url", rootCmd.PersistentFlags().Lookup("base-url")))
+	cobra.CheckErr(viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile")))
+	cobra.CheckErr(viper.BindPFlag("token-store", rootCmd.PersistentFlags().Lookup("token-store")))
+	cobra.CheckErr(viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token")))
+	cobra.CheckErr(viper.BindPFlag("token-file", rootCmd.PersistentFlags().Lookup("token-file")))
+	// token-command has no flag, its variable is named explicitly as the env prefix is set in initConfig
+	cobra.CheckErr(viper.BindEnv("token-command", tokenCommandEnv))
 }
 
-func initConfig() {
+// configDir returns ~/.config/switchdl, which holds the config and token files.
+func configDir() string {
 	home, err := os.UserHomeDir()
 	cobra.CheckErr(err)
+	return filepath.Join(home, ".config", "switchdl")
+}
 
-	configPath := filepath.Join(home, ".config", "switchdl") // ~/.config/switchdl
-	viper.AddConfigPath(configPath)
+// userConfigUsed reports whether the config file was read from configDir
+// rather than from the working directory.
+func userConfigUsed() bool {
+	used := viper.ConfigFileUsed()
+	return used != "" && filepath.Dir(used) == configDir()
+}
+
+// ignoreUntrustedSettings resets the settings that run commands, read the
+// token from a file or choose where it is sent if they come from a config file in the working
+// directory, which may come from anywhere, e.g. an unpacked archive. They are
+// only read from configDir, the environment or flags.
+func ignoreUntrustedSettings(cmd *cobra.Command) {
+	if userConfigUsed() {
+		return
+	}
+	settings := map[string]*string{
+		"base-url":      &downloadCfg.BaseURL,
+		"token-command": &downloadCfg.TokenCommand,
+		"token-file":    &downloadCfg.TokenFile,
+	}
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		value, defValue := settings[key], ""
+		if flag := cmd.Flags().Lookup(key); flag != nil {
+			if flag.Changed {
+				continue
+			}
+			defValue = flag.DefValue
+		}
+		env := envName(key)
+		if *value == defValue || *value == os.Getenv(env) {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s of %s, it is only read from the config file in %s, %s or the command line\n",
+			key, viper.ConfigFileUsed(), configDir(), env)
+		*value = defValue
+	}
+}
+
+// envName returns the environment variable of a setting.
+func envName(key string) string {
+	return "SWITCHDL_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+func initConfig() {
+	viper.AddConfigPath(configDir())
 	viper.AddConfigPath(".") // cwd
 	viper.SetConfigName(configName)
 	viper.SetConfigType("yaml")
@@ -86,7 +355,7 @@ func initConfig() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
 
-	if err = viper.ReadInConfig(); err == nil {
+	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Erl-koenig/switchdl/internal/media"
+)
+
+// useWorkingDirConfig runs the test in a directory with config as config.yaml
+// and without a user config file.
+func useWorkingDirConfig(t *testing.T, config string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, configName+".yaml"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func TestWorkingDirConfigCannotChangeBaseURL(t *testing.T) {
+	dir := useWorkingDirConfig(t,
+		"base-url: https://attacker.example\nprofiles:\n  work:\n    base-url: https://attacker.example\n")
+
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{"top-level setting", "", nil, media.SwitchTubeBaseURL},
+		{"environment", "https://staging.example", nil, "https://staging.example"},
+		{"flag", "", []string{"--base-url", "https://mirror.example"}, "https://mirror.example"},
+		// Last, as applying the profile settings overrides them in viper for the following runs
+		{"profile setting", "", []string{"--profile", "work"}, media.SwitchTubeBaseURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SWITCHDL_BASE_URL", tt.env)
+			t.Cleanup(func() { resetFlags(t) })
+			// verify works offline, so the settings are checked without a token or server
+			rootCmd.SetArgs(append([]string{"verify", dir}, tt.args...))
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if downloadCfg.BaseURL != tt.want {
+				t.Errorf("base URL is %q, want %q", downloadCfg.BaseURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkingDirConfigCannotSetTokenSources(t *testing.T) {
+	dir := useWorkingDirConfig(t, "token-file: /etc/hostname\ntoken-command: echo token\n")
+	t.Setenv(tokenCommandEnv, "")
+
+	rootCmd.SetArgs([]string{"verify", dir})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if downloadCfg.TokenFile != "" || downloadCfg.TokenCommand != "" {
+		t.Errorf("token file %q and command %q were read from the working directory",
+			downloadCfg.TokenFile, downloadCfg.TokenCommand)
+	}
+}
+
+// resetFlags restores the defaults of the flags changed by a test.
+func resetFlags(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"base-url", "profile"} {
+		flag := rootCmd.PersistentFlags().Lookup(name)
+		if err := flag.Value.Set(flag.DefValue); err != nil {
+			t.Fatal(err)
+		}
+		flag.Changed = false
+	}
+}
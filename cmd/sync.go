@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <id|url>",
+	Short: "Mirror one or multiple channels to the output directory",
+	Long: `Keep a local copy of one or more SwitchTube channels up to date.
+Each channel is stored in its own directory together with a state file, so only videos that are new
+or were republished since the last sync are downloaded. Videos removed from the channel are reported
+and can be moved to a .removed folder with --move-removed.`,
+	Example: `  switchdl sync abcdef1234 -o /path/to/courses
+  switchdl sync abcdef1234 ghijk56789 --move-removed`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newClient(downloadCfg.AccessToken)
+		downloadCfg.MoveRemoved = viper.GetBool("move-removed")
+
+		channelIDs, err := parseIDs(args, client.ParseChannelID)
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, channelID := range channelIDs {
+			downloadCfg.ChannelID = channelID
+			report, err := client.SyncChannel(cmd.Context(), &downloadCfg)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to sync channel %s: %w", channelID, err))
+				continue // keep syncing the remaining channels
+			}
+			client.PrintSyncReport(report)
+			if len(report.Failed) > 0 {
+				errs = append(errs, downloadFailures(
+					fmt.Sprintf("%d video(s) of channel %s failed", len(report.Failed), channelID), report.Failed))
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().Bool("move-removed", false, "Move files of videos removed from the channel to a .removed folder")
+	cobra.CheckErr(viper.BindPFlag("move-removed", syncCmd.Flags().Lookup("move-removed")))
+}
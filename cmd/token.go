@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Erl-koenig/switchdl/internal/keyringconfig"
+	"github.com/Erl-koenig/switchdl/internal/media"
+)
+
+const (
+	dateFormat      = "2006-01-02"
+	tokenCommandEnv = "SWITCHDL_TOKEN_COMMAND"
+)
+
+// tokenStore holds the access tokens, opened for all commands using the API
+var tokenStore keyringconfig.Store
+
+// openTokenStore opens the store selected by --token-store.
+func openTokenStore() error {
+	store, err := keyringconfig.OpenStore(keyringconfig.StoreOptions{
+		Backend:    downloadCfg.TokenStore,
+		Dir:        configDir(),
+		Passphrase: os.Getenv(keyringconfig.PassphraseEnv),
+	})
+	if err != nil {
+		return err
+	}
+	tokenStore = store
+	return nil
+}
+
+// tokenSources returns the sources given instead of a stored token.
+func tokenSources() keyringconfig.TokenSources {
+	return keyringconfig.TokenSources{
+		Token:   downloadCfg.AccessToken,
+		File:    downloadCfg.TokenFile,
+		Command: downloadCfg.TokenCommand,
+	}
+}
+
+func tokenUsageLog() *keyringconfig.UsageLog {
+	return keyringconfig.NewUsageLog(configDir())
+}
+
+// recordTokenUse records a successful request, a failure only affects the expiry warnings.
+func recordTokenUse(client *media.Client, token string) {
+	if err := tokenUsageLog().RecordUsed(token, time.Now()); err != nil {
+		client.Logger.Debug("failed to record token usage", "error", err)
+	}
+}
+
+// warnTokenExpiry warns if the token expires soon, because it wasn't used for a long time.
+func warnTokenExpiry(token string) {
+	usage, err := tokenUsageLog().Get(token)
+	if err != nil || !usage.ExpiresSoon(time.Now()) {
+		return
+	}
+	status := "expires around"
+	if usage.ExpiresAt().Before(time.Now()) {
+		status = "has probably expired on"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: the access token was last used on %s and %s %s.\n",
+		lastUse(usage).Format(dateFormat), status, usage.ExpiresAt().Format(dateFormat))
+}
+
+// printTokenUsage prints the age and estimated expiry of token.
+func printTokenUsage(token string) error {
+	usage, err := tokenUsageLog().Get(token)
+	if err != nil {
+		return err
+	}
+	if usage.FirstSeen.IsZero() {
+		fmt.Println("Its age is unknown, it wasn't used since switchdl started tracking it.")
+		return nil
+	}
+
+	now := time.Now()
+	fmt.Printf("Age: %s (since %s)\n", formatDays(now.Sub(usage.FirstSeen)), usage.FirstSeen.Format(dateFormat))
+	if usage.LastUsed.IsZero() {
+		fmt.Println("Last used: never")
+	} else {
+		fmt.Printf("Last used: %s\n", usage.LastUsed.Format(dateFormat))
+	}
+	expiresAt := usage.ExpiresAt()
+	if expiresAt.Before(now) {
+		fmt.Printf("Expires: probably expired on %s\n", expiresAt.Format(dateFormat))
+	} else {
+		fmt.Printf("Expires: around %s if not used (in %s)\n", expiresAt.Format(dateFormat), formatDays(expiresAt.Sub(now)))
+	}
+	return nil
+}
+
+// printLastUse explains an unauthorized token by its last successful use, if known.
+func printLastUse(token string) {
+	if token == "" {
+		return
+	}
+	usage, err := tokenUsageLog().Get(token)
+	if err != nil || usage.LastUsed.IsZero() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "It was last used successfully on %s, tokens expire after %s without use.\n",
+		usage.LastUsed.Format(dateFormat), formatDays(keyringconfig.TokenLifetime))
+}
+
+// lastUse returns when the token was last used, or stored if it never was.
+func lastUse(usage keyringconfig.TokenUsage) time.Time {
+	if usage.LastUsed.IsZero() {
+		return usage.FirstSeen
+	}
+	return usage.LastUsed
+}
+
+func formatDays(d time.Duration) string {
+	days := int(d.Hours() / 24) //nolint:mnd // hours per day
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
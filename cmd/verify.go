@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Erl-koenig/switchdl/internal/media"
+	"github.com/spf13/cobra"
+)
+
+// verifyResult is the output of verify in JSON mode, one line per file.
+type verifyResult struct {
+	File       string `json:"file"`
+	Valid      bool   `json:"valid"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <dir>",
+	Short: "Check downloaded MP4 files for corruption",
+	Long: `Check the structure of all MP4 files (.mp4 and .m4a) in one or more directories (including subdirectories).
+A file is reported as corrupt if its boxes are truncated or inconsistent, or if the ftyp, moov or mdat box is missing.
+Downloads are verified automatically, this command is meant for existing archives.`,
+	Example: `  switchdl verify /path/to/courses
+  switchdl verify . --output json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var checked, corrupt int
+		for _, dir := range args {
+			err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() || !isMP4File(path) {
+					return nil
+				}
+
+				checked++
+				duration, verifyErr := media.VerifyMP4(path, 0)
+				if verifyErr != nil {
+					corrupt++
+				}
+				return printVerifyResult(path, duration, verifyErr)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to verify %s: %w", dir, err)
+			}
+		}
+
+		if downloadCfg.Output == outputText {
+			fmt.Printf("\nVerified %d file(s), %d corrupt\n", checked, corrupt)
+		}
+		if corrupt > 0 {
+			return fmt.Errorf("%d of %d file(s) are corrupt", corrupt, checked)
+		}
+		return nil
+	},
+}
+
+// isMP4File reports whether the file has the extension of an MP4 video or audio file.
+func isMP4File(path string) bool {
+	ext := filepath.Ext(path)
+	return strings.EqualFold(ext, ".mp4") || strings.EqualFold(ext, ".m4a")
+}
+
+func printVerifyResult(path string, duration time.Duration, verifyErr error) error {
+	if downloadCfg.Output == outputJSON {
+		result := verifyResult{File: path, Valid: verifyErr == nil, DurationMs: duration.Milliseconds()}
+		if verifyErr != nil {
+			result.Error = verifyErr.Error()
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+			return fmt.Errorf("failed to write result: %w", err)
+		}
+		return nil
+	}
+
+	if verifyErr != nil {
+		fmt.Printf("CORRUPT  %s: %v\n", path, verifyErr)
+	} else {
+		fmt.Printf("OK       %s (%s)\n", path, duration.Round(time.Second))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"errors"
+	"slices"
 
 	"github.com/Erl-koenig/switchdl/internal/media"
 	"github.com/spf13/cobra"
@@ -9,15 +10,18 @@ import (
 )
 
 var videoCmd = &cobra.Command{
-	Use:   "video <id>",
+	Use:   "video <id|url>",
 	Short: "Download one or more videos specified by their id",
 	Example: `  switchdl video 1234567890
   switchdl video 1234567890 9876543210 3134859203
-  switchdl video 1234567890 -o /path/to/dir -f custom_name.mp4 -w -v`,
-	Args: cobra.MinimumNArgs(1),
+  switchdl video https://tube.switch.ch/videos/1234567890
+  switchdl video 1234567890 -o /path/to/dir -f custom_name.mp4 -w -v
+  switchdl video --batch-file course.txt`,
+	Args: argsOrBatchFile,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		filename := viper.GetString("filename")
-		if filename != "" && len(args) > 1 {
+		batchFile, _ := cmd.Flags().GetString(batchFileFlag)
+		if filename != "" && (len(args) > 1 || batchFile != "") {
 			return errors.New(
 				"custom filename (-f/--filename) can only be used when downloading a single video",
 			)
@@ -25,14 +29,37 @@ var videoCmd = &cobra.Command{
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		downloadCfg.VideoIDs = args
+		client := newClient(downloadCfg.AccessToken)
+		invalid := &media.DownloadSummary{Results: []media.DownloadResult{}}
+		videoIDs, err := parseIDs(args, client.ParseVideoID)
+		if err != nil {
+			return err
+		}
+		entries, err := readBatchFile(cmd, client, invalid, client.ParseVideoID)
+		if err != nil {
+			return err
+		}
+
+		downloadCfg.VideoIDs = videoIDs
 		downloadCfg.Filename = viper.GetString("filename")
+		downloadCfg.Videos = make(map[string]media.VideoOptions, len(entries))
+		for _, entry := range entries {
+			if slices.Contains(downloadCfg.VideoIDs, entry.ID) {
+				continue
+			}
+			downloadCfg.VideoIDs = append(downloadCfg.VideoIDs, entry.ID)
+			downloadCfg.Videos[entry.ID] = media.VideoOptions{Filename: entry.Filename, Subdir: entry.Subdir}
+		}
 
-		client := media.NewClient(downloadCfg.AccessToken)
-		summary := client.DownloadVideos(cmd.Context(), &downloadCfg)
+		summary := &media.DownloadSummary{Results: []media.DownloadResult{}}
+		if len(downloadCfg.VideoIDs) > 0 {
+			summary = client.DownloadVideos(cmd.Context(), &downloadCfg)
+		}
+		summary.Merge(invalid)
+		client.Events.WriteSummary(summary)
 
 		if summary.Succeeded == 0 {
-			return errors.New("failed to download any videos")
+			return downloadFailures("failed to download any videos", summary.Results)
 		}
 		return nil
 	},
@@ -42,4 +69,5 @@ func init() {
 	rootCmd.AddCommand(videoCmd)
 	videoCmd.Flags().StringP("filename", "f", "", "Output filename (defaults to video title)")
 	cobra.CheckErr(viper.BindPFlag("filename", videoCmd.Flags().Lookup("filename")))
+	addBatchFileFlag(videoCmd, "video")
 }
@@ -0,0 +1,213 @@
+package keyringconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"filippo.io/age"
+)
+
+const (
+	tokenFilePermissions = 0o600
+	tokenDirPermissions  = 0o700
+)
+
+// fileStore keeps the tokens as JSON object by profile name. With a
+// passphrase the file is encrypted with age, using an scrypt derived key.
+type fileStore struct {
+	path       string
+	passphrase string
+	tokens     map[string]string // Loaded once, as deriving the key is slow on purpose
+}
+
+func (s *fileStore) String() string {
+	if s.passphrase != "" {
+		return "the encrypted file " + s.path
+	}
+	return "the file " + s.path
+}
+
+func (s *fileStore) Get(profile string) (string, error) {
+	tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[profile]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+	return token, nil
+}
+
+func (s *fileStore) Set(profile, token string) error {
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[profile] = token
+	return s.save(tokens)
+}
+
+func (s *fileStore) Delete(profile string) error {
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[profile]; !ok {
+		return nil
+	}
+	delete(tokens, profile)
+	return s.save(tokens)
+}
+
+func (s *fileStore) Profiles() ([]string, error) {
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(tokens)), nil
+}
+
+func (s *fileStore) load() (map[string]string, error) {
+	if s.tokens != nil {
+		return s.tokens, nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	if s.passphrase == "" {
+		if err = checkPermissions(s.path); err != nil {
+			return nil, err
+		}
+	} else if data, err = s.decrypt(data); err != nil {
+		return nil, err
+	}
+
+	tokens := map[string]string{}
+	if err = json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+	s.tokens = tokens
+	return tokens, nil
+}
+
+func (s *fileStore) decrypt(data []byte) ([]byte, error) {
+	identity, err := age.NewScryptIdentity(s.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	reader, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token file %s, check %s: %w", s.path, PassphraseEnv, err)
+	}
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token file %s: %w", s.path, err)
+	}
+	return data, nil
+}
+
+func (s *fileStore) save(tokens map[string]string) error {
+	if len(tokens) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove token file: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+	if s.passphrase != "" {
+		if data, err = s.encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	if err = writeFile(s.path, data); err != nil {
+		return fmt.Errorf("failed to save token file: %w", err)
+	}
+	return nil
+}
+
+// writeFile replaces the file at path with data and mode 0600, so it is
+// never left partially written.
+func writeFile(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, tokenDirPermissions); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*") // created with mode 0600
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err = os.Chmod(file.Name(), tokenFilePermissions); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if err = os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
+func (s *fileStore) encrypt(data []byte) ([]byte, error) {
+	recipient, err := age.NewScryptRecipient(s.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	var buf bytes.Buffer
+	writer, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt tokens: %w", err)
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to encrypt tokens: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt tokens: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// checkPermissions refuses plaintext token files other users can access.
+// Windows has no such permission bits, access is controlled by ACLs there.
+func checkPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to check token file: %w", err)
+	}
+	if perm := info.Mode().Perm(); perm&^tokenFilePermissions != 0 {
+		return fmt.Errorf("%w: %s has mode %04o, run 'chmod 600 %s'", ErrTokenFilePermissions, path, perm, path)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package keyringconfig
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse battery staple"} {
+		path := filepath.Join(t.TempDir(), "switchdl", "tokens.json")
+		store := &fileStore{path: path, passphrase: passphrase}
+		if err := store.Set("default", "token-default"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Set("work", "token-work"); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted := !bytes.Contains(data, []byte("token-work")); encrypted != (passphrase != "") {
+			t.Errorf("passphrase %q: file is encrypted: %t", passphrase, encrypted)
+		}
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != tokenFilePermissions {
+				t.Errorf("token file has mode %04o, want %04o", perm, tokenFilePermissions)
+			}
+		}
+
+		// a new store reads the tokens from the file
+		reloaded := &fileStore{path: path, passphrase: passphrase}
+		if token, err := reloaded.Get("work"); err != nil || token != "token-work" {
+			t.Errorf("got token %q, %v, want token-work", token, err)
+		}
+		if _, err = reloaded.Get("missing"); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("got error %v for a missing profile, want %v", err, ErrTokenNotFound)
+		}
+		profiles, err := reloaded.Profiles()
+		if err != nil || !slices.Equal(profiles, []string{"default", "work"}) {
+			t.Errorf("got profiles %v, %v, want [default work]", profiles, err)
+		}
+
+		if err = reloaded.Delete("work"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = (&fileStore{path: path, passphrase: passphrase}).Get("work"); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("got error %v for a deleted profile, want %v", err, ErrTokenNotFound)
+		}
+		if err = reloaded.Delete("default"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("token file without tokens was not removed: %v", err)
+		}
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.age")
+	if err := (&fileStore{path: path, passphrase: "secret"}).Set("default", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&fileStore{path: path, passphrase: "other"}).Get("default"); err == nil {
+		t.Error("decrypted the token file with the wrong passphrase")
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits")
+	}
+	tests := []struct {
+		mode    os.FileMode
+		wantErr bool
+	}{
+		{0o600, false},
+		{0o400, false},
+		{0o640, true},
+		{0o644, true},
+		{0o606, true},
+		{0o700, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		if err := os.WriteFile(path, []byte(`{"default": "token"}`), tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, tt.mode); err != nil { // not affected by the umask
+			t.Fatal(err)
+		}
+
+		err := checkPermissions(path)
+		if tt.wantErr != errors.Is(err, ErrTokenFilePermissions) {
+			t.Errorf("mode %04o: got error %v, want permission error %t", tt.mode, err, tt.wantErr)
+		}
+		_, err = (&fileStore{path: path}).Get("default")
+		if tt.wantErr != errors.Is(err, ErrTokenFilePermissions) {
+			t.Errorf("mode %04o: reading the token got error %v, want permission error %t", tt.mode, err, tt.wantErr)
+		}
+	}
+}
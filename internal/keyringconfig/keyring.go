@@ -1,55 +1,167 @@
-// Package keyringconfig manages access token storage and retrieval using the system keyring
+// Package keyringconfig manages access token storage and retrieval using the system keyring or a token file
 package keyringconfig
 
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/zalando/go-keyring"
 )
 
 const (
-	Service = "switchdl" // Keyring service name
-	User    = "default"  // Default keyring user
+	Service        = "switchdl"  // Keyring service name
+	DefaultProfile = "default"   // Profile used without --profile, its keyring user is the profile name
+	indexUser      = "_profiles" // Keyring entry listing the profiles with a stored token
 )
 
-func GetAccessToken(currentToken string) (string, error) {
-	if currentToken != "" { // If already provided by flag
-		return currentToken, nil
+// ErrTokenNotFound is returned by GetAccessToken if the profile has no stored token.
+var ErrTokenNotFound = errors.New("access token not found")
+
+// Profile names are lower case, as viper lower cases the keys of the config file
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateProfile checks that name can be used as profile name.
+func ValidateProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf(
+			"invalid profile name %q, use lower case letters, digits, '-' and '_'",
+			name,
+		)
 	}
+	return nil
+}
 
-	token, err := keyring.Get(Service, User)
-	if err == nil {
-		return token, nil
+// GetAccessToken returns the token of the first source set, otherwise the one
+// stored for profile. The store isn't used if a source is set and may be nil then.
+func GetAccessToken(store Store, profile string, sources TokenSources) (string, error) {
+	if !sources.IsZero() { // If provided by flag, file or command
+		return sources.token()
+	}
+	if err := ValidateProfile(profile); err != nil {
+		return "", err
 	}
 
-	if !errors.Is(err, keyring.ErrNotFound) {
-		return "", fmt.Errorf(
-			"failed to access keyring: %w. Ensure the keyring service is running and you have appropriate permissions",
-			err,
-		)
+	token, err := store.Get(profile)
+	if !errors.Is(err, ErrTokenNotFound) {
+		return token, err
 	}
 
 	return "", fmt.Errorf(
-		"access token not found in keyring for service '%s' and user '%s'. Run 'switchdl configure' or provide it with the --token flag or SWITCHDL_TOKEN environment variable",
-		Service,
-		User,
+		"%w in %s for profile '%s'. Run '%s' or provide it with --token, --token-file, token-command or the SWITCHDL_TOKEN environment variable",
+		ErrTokenNotFound,
+		store,
+		profile,
+		ConfigureCommand(profile),
 	)
 }
 
-func SetAccessToken(token string) error {
+func SetAccessToken(store Store, profile, token string) error {
+	if err := ValidateProfile(profile); err != nil {
+		return err
+	}
 	if token == "" {
 		return errors.New("access token cannot be empty")
 	}
-	if err := keyring.Set(Service, User, token); err != nil {
+	return store.Set(profile, token)
+}
+
+func DeleteAccessToken(store Store, profile string) error {
+	if err := ValidateProfile(profile); err != nil {
+		return err
+	}
+	return store.Delete(profile)
+}
+
+// ConfigureCommand returns the command storing the token of profile.
+func ConfigureCommand(profile string) string {
+	if profile == DefaultProfile || profile == "" {
+		return "switchdl configure"
+	}
+	return "switchdl configure --profile " + profile
+}
+
+// keyringStore keeps the tokens in the OS keyring, with the profile as user.
+type keyringStore struct{}
+
+// keyringAvailable reports whether the OS keyring can be reached, e.g. it
+// can't on Linux servers without a Secret Service daemon.
+func keyringAvailable() bool {
+	_, err := keyring.Get(Service, indexUser)
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+func (keyringStore) String() string {
+	return "the OS keyring"
+}
+
+func (keyringStore) Get(profile string) (string, error) {
+	token, err := keyring.Get(Service, profile)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrTokenNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to access keyring: %w. Ensure the keyring service is running and you have appropriate permissions",
+			err,
+		)
+	}
+	return token, nil
+}
+
+func (s keyringStore) Set(profile, token string) error {
+	if err := keyring.Set(Service, profile, token); err != nil {
 		return fmt.Errorf("failed to save token to keyring: %w", err)
 	}
-	return nil
+	return s.updateIndex(func(profiles []string) []string {
+		if slices.Contains(profiles, profile) {
+			return profiles
+		}
+		return append(profiles, profile)
+	})
 }
 
-func DeleteAccessToken() error {
-	if err := keyring.Delete(Service, User); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+func (s keyringStore) Delete(profile string) error {
+	if err := keyring.Delete(Service, profile); err != nil && !errors.Is(err, keyring.ErrNotFound) {
 		return fmt.Errorf("failed to delete token: %w", err)
 	}
+	return s.updateIndex(func(profiles []string) []string {
+		return slices.DeleteFunc(profiles, func(name string) bool { return name == profile })
+	})
+}
+
+// Profiles reads the names from a separate entry, as the keyring can't be enumerated.
+func (keyringStore) Profiles() ([]string, error) {
+	index, err := keyring.Get(Service, indexUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles from keyring: %w", err)
+	}
+	profiles := strings.Fields(index)
+	slices.Sort(profiles)
+	return slices.Compact(profiles), nil
+}
+
+func (s keyringStore) updateIndex(update func([]string) []string) error {
+	profiles, err := s.Profiles()
+	if err != nil {
+		return err
+	}
+	profiles = update(profiles)
+	if len(profiles) == 0 {
+		err = keyring.Delete(Service, indexUser)
+		if errors.Is(err, keyring.ErrNotFound) {
+			err = nil
+		}
+	} else {
+		err = keyring.Set(Service, indexUser, strings.Join(profiles, "\n"))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update profiles in keyring: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,72 @@
+package keyringconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// TokenSources provide the token instead of the store. They are tried in the
+// order of the fields, the first one set is used.
+type TokenSources struct {
+	Token   string // Given with --token or SWITCHDL_TOKEN
+	File    string // Path of a file containing the token, e.g. a Docker or Kubernetes secret
+	Command string // Shell command printing the token, e.g. of a password manager
+}
+
+// IsZero reports whether no source is set, so the token has to come from the store.
+func (s TokenSources) IsZero() bool {
+	return s == TokenSources{}
+}
+
+func (s TokenSources) token() (string, error) {
+	switch {
+	case s.Token != "":
+		return s.Token, nil
+	case s.File != "":
+		return readTokenFile(s.File)
+	case s.Command != "":
+		return runTokenCommand(s.Command)
+	default:
+		return "", nil
+	}
+}
+
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// runTokenCommand returns the first line the command prints, like "pass show"
+// prints the password followed by other fields. Stdin and stderr are passed
+// through, so the command can ask for a passphrase.
+func runTokenCommand(command string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command %q failed: %w", command, err)
+	}
+
+	line, _, _ := strings.Cut(string(output), "\n")
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errors.New("token command printed no token")
+	}
+	return token, nil
+}
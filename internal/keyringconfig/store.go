@@ -0,0 +1,94 @@
+package keyringconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Values of the token-store setting
+const (
+	BackendAuto          = "auto" // OS keyring, or a file if no keyring is available
+	BackendKeyring       = "keyring"
+	BackendEncryptedFile = "encrypted-file"
+	BackendFile          = "file"
+)
+
+// File names of the file backends, in the config directory
+const (
+	encryptedFileName = "tokens.age"
+	plainFileName     = "tokens.json"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase of the encrypted file.
+const PassphraseEnv = "SWITCHDL_TOKEN_PASSPHRASE"
+
+// ErrTokenFilePermissions is returned if the plaintext token file can be read by other users.
+var ErrTokenFilePermissions = errors.New("token file is accessible by other users")
+
+// Store keeps the access tokens of the profiles.
+type Store interface {
+	// Get returns ErrTokenNotFound if the profile has no token.
+	Get(profile string) (string, error)
+	Set(profile, token string) error
+	// Delete succeeds if the profile has no token.
+	Delete(profile string) error
+	// Profiles returns the sorted names of the profiles with a token.
+	Profiles() ([]string, error)
+	// String describes the backend for messages, e.g. "the OS keyring".
+	String() string
+}
+
+// StoreOptions select and configure the token store.
+type StoreOptions struct {
+	Backend    string // One of the Backend constants, empty for BackendAuto
+	Dir        string // Directory of the token files
+	Passphrase string // Passphrase of the encrypted file, required by that backend
+}
+
+// OpenStore returns the store selected by opts. With BackendAuto the OS
+// keyring is used if it is reachable, otherwise the encrypted file if a
+// passphrase is set and the plaintext file as last resort.
+func OpenStore(opts StoreOptions) (Store, error) {
+	encrypted := &fileStore{
+		path:       filepath.Join(opts.Dir, encryptedFileName),
+		passphrase: opts.Passphrase,
+	}
+	plain := &fileStore{path: filepath.Join(opts.Dir, plainFileName)}
+
+	switch opts.Backend {
+	case BackendKeyring:
+		return keyringStore{}, nil
+	case BackendEncryptedFile:
+		if opts.Passphrase == "" {
+			return nil, errNoPassphrase(encrypted.path)
+		}
+		return encrypted, nil
+	case BackendFile:
+		return plain, nil
+	case BackendAuto, "":
+		if keyringAvailable() {
+			return keyringStore{}, nil
+		}
+		if opts.Passphrase != "" {
+			return encrypted, nil
+		}
+		if _, err := os.Stat(encrypted.path); err == nil {
+			return nil, errNoPassphrase(encrypted.path)
+		}
+		return plain, nil
+	default:
+		return nil, fmt.Errorf(
+			"invalid token store %q, must be %q, %q, %q or %q",
+			opts.Backend, BackendAuto, BackendKeyring, BackendEncryptedFile, BackendFile,
+		)
+	}
+}
+
+func errNoPassphrase(path string) error {
+	return fmt.Errorf(
+		"the token file %s is encrypted, set its passphrase with the %s environment variable",
+		path, PassphraseEnv,
+	)
+}
@@ -0,0 +1,130 @@
+package keyringconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	TokenLifetime = 60 * 24 * time.Hour // SwitchTube tokens expire when not used for this long
+	ExpiryWarning = 7 * 24 * time.Hour  // Commands warn if the token expires within this time
+	usageFileName = "token-usage.json"
+)
+
+// TokenUsage tells when a token was first seen and last used successfully.
+type TokenUsage struct {
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastUsed  time.Time `json:"last_used,omitzero"`
+}
+
+// ExpiresAt estimates when the token expires if it isn't used, zero if it was never seen.
+func (u TokenUsage) ExpiresAt() time.Time {
+	last := u.LastUsed
+	if last.IsZero() {
+		last = u.FirstSeen
+	}
+	if last.IsZero() {
+		return time.Time{}
+	}
+	return last.Add(TokenLifetime)
+}
+
+// ExpiresSoon reports whether the token expires within ExpiryWarning or already expired.
+func (u TokenUsage) ExpiresSoon(now time.Time) bool {
+	expiresAt := u.ExpiresAt()
+	return !expiresAt.IsZero() && expiresAt.Sub(now) < ExpiryWarning
+}
+
+// UsageLog keeps the usage of tokens in a file. Tokens are identified by a
+// hash, so the file doesn't reveal them.
+type UsageLog struct {
+	path string
+}
+
+// NewUsageLog returns the log kept in dir, next to the token files.
+func NewUsageLog(dir string) *UsageLog {
+	return &UsageLog{path: filepath.Join(dir, usageFileName)}
+}
+
+// Get returns the usage of token, zero if it wasn't recorded yet.
+func (l *UsageLog) Get(token string) (TokenUsage, error) {
+	usages, err := l.load()
+	if err != nil {
+		return TokenUsage{}, err
+	}
+	return usages[tokenHash(token)], nil
+}
+
+// RecordStored records that token was stored at the given time, which starts its age.
+func (l *UsageLog) RecordStored(token string, at time.Time) error {
+	return l.update(token, at, func(usage *TokenUsage) {
+		if usage.FirstSeen.IsZero() {
+			usage.FirstSeen = at
+		}
+	})
+}
+
+// RecordUsed records a successful request with token at the given time.
+func (l *UsageLog) RecordUsed(token string, at time.Time) error {
+	return l.update(token, at, func(usage *TokenUsage) {
+		if usage.FirstSeen.IsZero() {
+			usage.FirstSeen = at
+		}
+		usage.LastUsed = at
+	})
+}
+
+func (l *UsageLog) update(token string, now time.Time, update func(*TokenUsage)) error {
+	usages, err := l.load()
+	if err != nil {
+		return err
+	}
+	key := tokenHash(token)
+	usage := usages[key]
+	update(&usage)
+	usages[key] = usage
+
+	// Forget tokens that expired long ago, e.g. replaced ones
+	for key, usage := range usages {
+		if now.Sub(usage.ExpiresAt()) > TokenLifetime {
+			delete(usages, key)
+		}
+	}
+
+	data, err := json.MarshalIndent(usages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode token usage: %w", err)
+	}
+	if err = writeFile(l.path, data); err != nil {
+		return fmt.Errorf("failed to save token usage: %w", err)
+	}
+	return nil
+}
+
+func (l *UsageLog) load() (map[string]TokenUsage, error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]TokenUsage{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token usage: %w", err)
+	}
+	usages := map[string]TokenUsage{}
+	if err = json.Unmarshal(data, &usages); err != nil {
+		return nil, fmt.Errorf("failed to parse token usage %s: %w", l.path, err)
+	}
+	return usages, nil
+}
+
+// tokenHash identifies a token without revealing it.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16]) //nolint:mnd // 128 bits are enough to tell tokens apart
+}
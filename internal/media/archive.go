@@ -0,0 +1,110 @@
+package media
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadArchive records successfully downloaded videos, so they are never
+// fetched twice even if the files were renamed or moved. The file has one
+// tab-separated entry per line: video ID, variant name and download time.
+type DownloadArchive struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]ArchiveEntry
+}
+
+type ArchiveEntry struct {
+	VideoID      string
+	Variant      string
+	DownloadedAt time.Time
+}
+
+func OpenDownloadArchive(path string) (*DownloadArchive, error) {
+	archive := &DownloadArchive{
+		path:    path,
+		entries: make(map[string]ArchiveEntry),
+	}
+	if err := archive.load(); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *DownloadArchive) load() (err error) {
+	file, err := os.Open(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // created on the first recorded download
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open download archive: %w", err)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close download archive: %w", cerr)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		entry := ArchiveEntry{VideoID: fields[0]}
+		if len(fields) > 1 {
+			entry.Variant = fields[1]
+		}
+		if len(fields) > 2 { //nolint:mnd // third column is the timestamp
+			entry.DownloadedAt, _ = time.Parse(time.RFC3339, fields[2])
+		}
+		a.entries[entry.VideoID] = entry
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read download archive: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether the video was already downloaded. A nil archive contains nothing.
+func (a *DownloadArchive) Contains(videoID string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.entries[videoID]
+	return ok
+}
+
+// Record appends the video to the archive file. Appending keeps entries
+// written by other machines sharing the same file.
+func (a *DownloadArchive) Record(videoID, variant string) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry := ArchiveEntry{VideoID: videoID, Variant: variant, DownloadedAt: time.Now().UTC()}
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, DefaultFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to open download archive: %w", err)
+	}
+	line := fmt.Sprintf("%s\t%s\t%s\n", entry.VideoID, entry.Variant, entry.DownloadedAt.Format(time.RFC3339))
+	if _, err = file.WriteString(line); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write download archive: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close download archive: %w", err)
+	}
+	a.entries[videoID] = entry
+	return nil
+}
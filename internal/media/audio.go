@@ -0,0 +1,388 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const audioExtension = ".m4a"
+
+var errNoAudioTrack = errors.New("no audio track found")
+
+// mediaChunk is a run of consecutive samples of a track in the mdat box.
+type mediaChunk struct {
+	offset int64
+	size   int64
+}
+
+// audioPath returns the path of the .m4a file extracted from videoFile.
+func audioPath(videoFile string) string {
+	return strings.TrimSuffix(videoFile, filepath.Ext(videoFile)) + audioExtension
+}
+
+// extractJobAudio writes the audio track of the job's download to an .m4a file
+// next to it and deletes the video if requested.
+func (c *Client) extractJobAudio(p *downloadProgress, job *downloadJob) error {
+	name := filepath.Base(job.outputFile)
+	switch {
+	case strings.HasPrefix(baseMediaType(job.mediaType), "audio/"):
+		fmt.Fprintf(p, "Not extracting audio from \"%s\", it is an audio file already\n", name)
+		return nil
+	case !isMP4MediaType(job.mediaType):
+		fmt.Fprintf(p, "Not extracting audio from \"%s\", only MP4 files are supported\n", name)
+		return nil
+	}
+
+	audioFile := job.audioFile
+	if err := extractAudio(job.outputFile, audioFile); err != nil {
+		return err
+	}
+	if job.verify {
+		expected := time.Duration(job.details.DurationInMilliseconds) * time.Millisecond
+		if _, err := VerifyMP4(audioFile, expected); err != nil {
+			_ = os.Remove(audioFile)
+			return fmt.Errorf("extracted audio is corrupt: %w", err)
+		}
+	}
+	fmt.Fprintf(p, "Extracted audio to \"%s\"\n", filepath.Base(audioFile))
+
+	if job.deleteVideo {
+		if err := os.Remove(job.outputFile); err != nil {
+			return fmt.Errorf("failed to delete video: %w", err)
+		}
+		job.outputFile = audioFile
+	}
+	return nil
+}
+
+// extractAudio copies the first audio track of an MP4 file into an M4A file,
+// i.e. an MP4 container with only this track. The samples are copied as they
+// are, so no decoder is needed. The file is written next to dst and renamed.
+func extractAudio(src, dst string) error {
+	tmpName, err := writeAudioFile(src, dst)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, dst); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to rename %s: %w", tmpName, err)
+	}
+	return nil
+}
+
+func writeAudioFile(src, dst string) (_ string, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer func() {
+		if cerr := in.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", src, cerr)
+		}
+	}()
+
+	info, err := in.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	boxes, err := readBoxes(in, 0, info.Size())
+	if err != nil {
+		return "", err
+	}
+	moovBox, ok := findBox(boxes, "moov")
+	if !ok {
+		return "", fmt.Errorf("%w: no moov box", errInvalidMP4)
+	}
+	moov := make([]byte, moovBox.Size)
+	if _, err = in.ReadAt(moov, moovBox.Offset); err != nil {
+		return "", fmt.Errorf("failed to read moov box: %w", err)
+	}
+
+	children, err := readBoxes(bytes.NewReader(moov), moovBox.HeaderSize, int64(len(moov)))
+	if err != nil {
+		return "", err
+	}
+	trak, err := findAudioTrak(moov, children)
+	if err != nil {
+		return "", err
+	}
+	chunks, err := trackChunks(moov, trak, info.Size())
+	if err != nil {
+		return "", err
+	}
+	var dataSize int64
+	for _, chunk := range chunks {
+		if chunk.offset < 0 || chunk.offset+chunk.size > info.Size() {
+			return "", fmt.Errorf("%w: chunk at offset %d exceeds the file", errInvalidMP4, chunk.offset)
+		}
+		dataSize += chunk.size
+	}
+
+	ftyp := m4aFileType()
+	// The size of moov doesn't depend on the offsets, so build it once to find
+	// where the media data starts and again with the final offsets
+	newMoov, err := audioMoov(moov, children, trak, chunks, 0)
+	if err != nil {
+		return "", err
+	}
+	dataStart := int64(len(ftyp)+len(newMoov)) + boxHeaderSize
+	if dataStart+dataSize > math.MaxUint32 {
+		return "", errors.New("audio track is too large for an M4A file with 32-bit offsets")
+	}
+	if newMoov, err = audioMoov(moov, children, trak, chunks, dataStart); err != nil {
+		return "", err
+	}
+
+	return writeTempFile(dst, func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		mdatHeader := binary.BigEndian.AppendUint32(nil, uint32(boxHeaderSize+dataSize)) //nolint:gosec // checked above
+		for _, part := range [][]byte{ftyp, newMoov, append(mdatHeader, "mdat"...)} {
+			if _, err := w.Write(part); err != nil {
+				return err
+			}
+		}
+		for _, chunk := range chunks {
+			if _, err := io.Copy(w, io.NewSectionReader(in, chunk.offset, chunk.size)); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+}
+
+// m4aFileType returns the ftyp box of an iTunes-compatible audio file.
+func m4aFileType() []byte {
+	payload := []byte("M4A ")
+	payload = binary.BigEndian.AppendUint32(payload, 0x200) //nolint:mnd // minor version used by iTunes
+	payload = append(payload, "M4A mp42isom"...)
+	return makeBox("ftyp", payload)
+}
+
+// findAudioTrak returns the first trak whose media handler is "soun".
+func findAudioTrak(moov []byte, children []mp4Box) (mp4Box, error) {
+	for _, child := range children {
+		if child.Type == "mvex" {
+			return mp4Box{}, errFragmentedMP4
+		}
+		if child.Type != "trak" {
+			continue
+		}
+		hdlr, err := descend(moov, child, "mdia", "hdlr")
+		if err != nil {
+			continue
+		}
+		payload := moov[hdlr.payloadOffset():hdlr.end()]
+		if len(payload) >= 12 && string(payload[8:12]) == "soun" {
+			return child, nil
+		}
+	}
+	return mp4Box{}, errNoAudioTrack
+}
+
+// audioMoov returns the moov box with the given trak as the only track, whose
+// chunks are stored consecutively starting at dataStart.
+func audioMoov(moov []byte, children []mp4Box, trak mp4Box, chunks []mediaChunk, dataStart int64) ([]byte, error) {
+	offsets := binary.BigEndian.AppendUint32(nil, 0)                      // version and flags
+	offsets = binary.BigEndian.AppendUint32(offsets, uint32(len(chunks))) //nolint:gosec // count read from a 32-bit field
+	position := dataStart
+	for _, chunk := range chunks {
+		offsets = binary.BigEndian.AppendUint32(offsets, uint32(position)) //nolint:gosec // checked by the caller
+		position += chunk.size
+	}
+	stco := makeBox("stco", offsets)
+
+	var payload []byte
+	for _, child := range children {
+		switch {
+		case child.Type == "trak" && child.Offset == trak.Offset:
+			newTrak, err := replaceChunkOffsets(moov, child, stco)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, newTrak...)
+		case child.Type == "trak": // other tracks are dropped
+		default:
+			payload = append(payload, moov[child.Offset:child.end()]...)
+		}
+	}
+	return makeBox("moov", payload), nil
+}
+
+// replaceChunkOffsets returns a copy of box with its stco or co64 box replaced by stco.
+func replaceChunkOffsets(data []byte, box mp4Box, stco []byte) ([]byte, error) {
+	switch {
+	case box.Type == "stco" || box.Type == "co64":
+		return stco, nil
+	case box.Type == "trak" || box.Type == "mdia" || box.Type == "minf" || box.Type == "stbl":
+		children, err := readBoxes(bytes.NewReader(data), box.payloadOffset(), box.end())
+		if err != nil {
+			return nil, err
+		}
+		var payload []byte
+		for _, child := range children {
+			childData, err := replaceChunkOffsets(data, child, stco)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, childData...)
+		}
+		return makeBox(box.Type, payload), nil
+	default:
+		return data[box.Offset:box.end()], nil
+	}
+}
+
+// descend returns the box reached by following path from parent.
+func descend(data []byte, parent mp4Box, path ...string) (mp4Box, error) {
+	box := parent
+	for _, boxType := range path {
+		children, err := readBoxes(bytes.NewReader(data), box.payloadOffset(), box.end())
+		if err != nil {
+			return mp4Box{}, err
+		}
+		child, ok := findBox(children, boxType)
+		if !ok {
+			return mp4Box{}, fmt.Errorf("%w: no %s box in %s", errInvalidMP4, boxType, box.Type)
+		}
+		box = child
+	}
+	return box, nil
+}
+
+// trackChunks returns the location of all chunks of a track from its sample
+// table. The samples must fit into a file of fileSize bytes.
+func trackChunks(moov []byte, trak mp4Box, fileSize int64) ([]mediaChunk, error) {
+	stbl, err := descend(moov, trak, "mdia", "minf", "stbl")
+	if err != nil {
+		return nil, err
+	}
+	tables, err := readBoxes(bytes.NewReader(moov), stbl.payloadOffset(), stbl.end())
+	if err != nil {
+		return nil, err
+	}
+	payload := func(boxType string) []byte {
+		box, ok := findBox(tables, boxType)
+		if !ok {
+			return nil
+		}
+		return moov[box.payloadOffset():box.end()]
+	}
+
+	sizes, err := parseSampleSizes(payload("stsz"), fileSize)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := parseChunkOffsets(payload("stco"), payload("co64"))
+	if err != nil {
+		return nil, err
+	}
+	samplesPerChunk, err := parseSampleToChunk(payload("stsc"), len(offsets))
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]mediaChunk, len(offsets))
+	sample := 0
+	for i, offset := range offsets {
+		end := sample + samplesPerChunk[i]
+		if end > len(sizes) {
+			return nil, fmt.Errorf("%w: sample table references %d samples, but only %d exist", errInvalidMP4, end, len(sizes))
+		}
+		chunks[i].offset = offset
+		for _, size := range sizes[sample:end] {
+			chunks[i].size += size
+		}
+		sample = end
+	}
+	return chunks, nil
+}
+
+// parseSampleSizes returns the size of each sample from an stsz payload. A
+// uniform size is only expanded for as many samples as fit into fileSize.
+func parseSampleSizes(data []byte, fileSize int64) ([]int64, error) {
+	const header = 12 // version/flags, sample size, sample count
+	if len(data) < header {
+		return nil, fmt.Errorf("%w: missing or truncated stsz box", errInvalidMP4)
+	}
+	uniform := int64(binary.BigEndian.Uint32(data[4:8]))
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+	if uniform == 0 && len(data) < header+count*4 {
+		return nil, fmt.Errorf("%w: truncated stsz box", errInvalidMP4)
+	}
+	if uniform != 0 && int64(count) > fileSize/uniform {
+		return nil, fmt.Errorf("%w: stsz box has %d samples of %d bytes, more than the file holds", errInvalidMP4, count, uniform)
+	}
+
+	sizes := make([]int64, count)
+	for i := range sizes {
+		sizes[i] = uniform
+		if uniform == 0 {
+			sizes[i] = int64(binary.BigEndian.Uint32(data[header+i*4:]))
+		}
+	}
+	return sizes, nil
+}
+
+// parseChunkOffsets returns the chunk offsets of an stco or co64 payload, whichever is set.
+func parseChunkOffsets(stco, co64 []byte) ([]int64, error) {
+	data, width := stco, 4
+	if data == nil {
+		data, width = co64, 8
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: missing or truncated chunk offset box", errInvalidMP4)
+	}
+	count := int(binary.BigEndian.Uint32(data[4:8]))
+	if len(data) < 8+count*width {
+		return nil, fmt.Errorf("%w: truncated chunk offset box", errInvalidMP4)
+	}
+
+	offsets := make([]int64, count)
+	for i := range offsets {
+		if width == 4 {
+			offsets[i] = int64(binary.BigEndian.Uint32(data[8+i*4:]))
+		} else {
+			offsets[i] = int64(binary.BigEndian.Uint64(data[8+i*8:])) //nolint:gosec // validated against the file size
+		}
+	}
+	return offsets, nil
+}
+
+// parseSampleToChunk expands an stsc payload into the number of samples of each chunk.
+func parseSampleToChunk(data []byte, chunkCount int) ([]int, error) {
+	const entrySize = 12 // first chunk, samples per chunk, sample description index
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: missing or truncated stsc box", errInvalidMP4)
+	}
+	count := int(binary.BigEndian.Uint32(data[4:8]))
+	if len(data) < 8+count*entrySize {
+		return nil, fmt.Errorf("%w: truncated stsc box", errInvalidMP4)
+	}
+
+	samples := make([]int, chunkCount)
+	for i := range count {
+		entry := data[8+i*entrySize:]
+		first := int(binary.BigEndian.Uint32(entry[0:4])) // 1-based
+		perChunk := int(binary.BigEndian.Uint32(entry[4:8]))
+		last := chunkCount
+		if i+1 < count {
+			last = int(binary.BigEndian.Uint32(data[8+(i+1)*entrySize:])) - 1
+		}
+		if first < 1 || last > chunkCount || first > last+1 {
+			return nil, fmt.Errorf("%w: invalid stsc entry for chunk %d", errInvalidMP4, first)
+		}
+		for chunk := first; chunk <= last; chunk++ {
+			samples[chunk-1] = perChunk
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,207 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The mdat of avFile interleaves the chunks of both tracks: video, audio,
+// video, audio, audio. The audio chunks hold 2, 2 and 1 samples.
+var (
+	avChunks = [][]byte{
+		bytes.Repeat([]byte("v"), 100),
+		append(bytes.Repeat([]byte("a"), 10), bytes.Repeat([]byte("b"), 20)...),
+		bytes.Repeat([]byte("w"), 50),
+		append(bytes.Repeat([]byte("c"), 30), bytes.Repeat([]byte("d"), 5)...),
+		bytes.Repeat([]byte("e"), 7),
+	}
+	audioSampleSizes = []int64{10, 20, 30, 5, 7}
+	audioStsc        = box("stsc", u32s(0, 2, 1, 2, 1, 3, 1, 1)) // chunks 1-2: 2 samples, chunk 3: 1 sample
+)
+
+// avMoov returns a moov builder with a video track using stco and an audio
+// track using co64. audioTables replaces the stsz and stsc boxes of the audio track.
+func avMoov(audioTables ...[]byte) func(offsets []int64) []byte {
+	if audioTables == nil {
+		audioTables = [][]byte{box("stsz", u32s(0, 0, 5, 10, 20, 30, 5, 7)), audioStsc}
+	}
+	return func(offsets []int64) []byte {
+		video := trakBox("vide",
+			box("stsd", u32s(0, 0)),
+			box("stsz", u32s(0, 0, 2, 100, 50)),
+			box("stsc", u32s(0, 1, 1, 1, 1)),
+			chunkOffsetBox(false, offsets[0], offsets[2]),
+		)
+		audioBoxes := append([][]byte{box("stsd", u32s(0, 0))}, audioTables...)
+		audioBoxes = append(audioBoxes, chunkOffsetBox(true, offsets[1], offsets[3], offsets[4]))
+		audio := trakBox("soun", audioBoxes...)
+		return box("moov", mvhdBox(1000, 60000), video, audio, box("udta", box("cprt", []byte("kept"))))
+	}
+}
+
+func TestExtractAudio(t *testing.T) {
+	for _, moovFirst := range []bool{true, false} {
+		src := writeTestFile(t, "video.mp4", buildMP4(moovFirst, avMoov(), avChunks...))
+		dst := audioPath(src)
+
+		if err := extractAudio(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := VerifyMP4(dst, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		boxes, err := readBoxes(bytes.NewReader(data), 0, int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data[8:], []byte("M4A ")) {
+			t.Errorf("file type is %q, want M4A", data[8:12])
+		}
+		moov, _ := findBox(boxes, "moov")
+		mdat, _ := findBox(boxes, "mdat")
+		children, err := readBoxes(bytes.NewReader(data), moov.payloadOffset(), moov.end())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []string
+		for _, child := range children {
+			types = append(types, child.Type)
+		}
+		if want := []string{"mvhd", "trak", "udta"}; !slices.Equal(types, want) {
+			t.Fatalf("moov holds %v, want %v", types, want)
+		}
+
+		// The audio chunks are stored consecutively at the start of mdat
+		start := mdat.payloadOffset()
+		wantOffsets := []int64{start, start + 30, start + 65}
+		if offsets := readChunkOffsets(t, data); !slices.Equal(offsets, wantOffsets) {
+			t.Errorf("chunk offsets are %v, want %v", offsets, wantOffsets)
+		}
+		if want := bytes.Join([][]byte{avChunks[1], avChunks[3], avChunks[4]}, nil); !bytes.Equal(data[start:], want) {
+			t.Errorf("media data is %q, want %q", data[start:], want)
+		}
+
+		trak, _ := findBox(children, "trak")
+		stbl, err := descend(data, trak, "mdia", "minf", "stbl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables, err := readBoxes(bytes.NewReader(data), stbl.payloadOffset(), stbl.end())
+		if err != nil {
+			t.Fatal(err)
+		}
+		stsz, _ := findBox(tables, "stsz")
+		sizes, err := parseSampleSizes(data[stsz.payloadOffset():stsz.end()], int64(len(data)))
+		if err != nil || !slices.Equal(sizes, audioSampleSizes) {
+			t.Errorf("sample sizes are %v (%v), want %v", sizes, err, audioSampleSizes)
+		}
+		stsc, _ := findBox(tables, "stsc")
+		if !bytes.Equal(data[stsc.Offset:stsc.end()], audioStsc) {
+			t.Errorf("stsc box is %x, want %x", data[stsc.Offset:stsc.end()], audioStsc)
+		}
+	}
+}
+
+func TestExtractAudioRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		moov    func(offsets []int64) []byte
+		wantErr error
+	}{
+		{"no audio track", videoMoov(false, nil), errNoAudioTrack},
+		{"more samples referenced than sized", avMoov(box("stsz", u32s(0, 0, 3, 10, 20, 30)), audioStsc), errInvalidMP4},
+		{"uniform samples exceeding the file", avMoov(box("stsz", u32s(0, 1<<20, 5)), audioStsc), errInvalidMP4},
+		{"missing stsc", avMoov(box("stsz", u32s(0, 0, 5, 10, 20, 30, 5, 7))), errInvalidMP4},
+		{"chunk beyond the file", func(offsets []int64) []byte {
+			return avMoov()(append(offsets[:4:4], 1<<40))
+		}, errInvalidMP4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := writeTestFile(t, "video.mp4", buildMP4(true, tt.moov, avChunks...))
+			dst := audioPath(src)
+
+			if err := extractAudio(src, dst); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(dst); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("audio file was written: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSampleToChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []uint32 // first chunk, samples per chunk, description index
+		want    []int
+	}{
+		{"single run", []uint32{1, 4, 1}, []int{4, 4, 4}},
+		{"several runs", []uint32{1, 2, 1, 3, 1, 1}, []int{2, 2, 1}},
+		{"first chunk 0", []uint32{0, 2, 1}, nil},
+		{"first chunk beyond the chunks", []uint32{1, 2, 1, 5, 1, 1}, nil},
+		{"decreasing first chunks", []uint32{1, 2, 1, 3, 1, 1, 2, 1, 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := u32s(0, uint32(len(tt.entries)/3))
+			data = append(data, u32s(tt.entries...)...)
+			got, err := parseSampleToChunk(data, 3)
+			if tt.want == nil {
+				if !errors.Is(err, errInvalidMP4) {
+					t.Errorf("got %v, %v, want an invalid MP4 error", got, err)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	for _, data := range [][]byte{nil, u32s(0), u32s(0, 2, 1, 2, 1)} {
+		if _, err := parseSampleToChunk(data, 3); !errors.Is(err, errInvalidMP4) {
+			t.Errorf("truncated stsc %x: got error %v, want an invalid MP4 error", data, err)
+		}
+	}
+}
+
+func TestParseSampleSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []int64
+	}{
+		{"per sample", u32s(0, 0, 3, 10, 20, 30), []int64{10, 20, 30}},
+		{"uniform", u32s(0, 8, 3), []int64{8, 8, 8}},
+		{"no samples", u32s(0, 0, 0), []int64{}},
+		{"missing", nil, nil},
+		{"truncated header", u32s(0, 0), nil},
+		{"truncated sizes", u32s(0, 0, 3, 10, 20), nil},
+		{"uniform samples exceeding the file", u32s(0, 8, 1<<31), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSampleSizes(tt.data, 1000)
+			if tt.want == nil {
+				if !errors.Is(err, errInvalidMP4) {
+					t.Errorf("got %v, %v, want an invalid MP4 error", got, err)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
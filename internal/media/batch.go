@@ -0,0 +1,129 @@
+package media
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BatchEntry is a video or channel listed in a batch file. A line consists of
+// an ID or URL, optionally followed by overrides, e.g.
+//
+//	1234567890 filename="Lecture 01" subdir=week1  # comment
+type BatchEntry struct {
+	Line     int
+	ID       string // As written, i.e. an ID or URL
+	Filename string // Custom output filename, only for videos
+	Subdir   string // Subdirectory of the output directory
+	Err      error  // Set if the line is invalid, the other fields may be incomplete
+}
+
+// ReadBatchFile reads the entries of a batch file, "-" reads standard input.
+// Blank lines and comments starting with # are ignored.
+func ReadBatchFile(path string) (_ []BatchEntry, err error) {
+	if path == "-" {
+		return ParseBatch(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch file: %w", err)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close batch file: %w", cerr)
+		}
+	}()
+	return ParseBatch(file)
+}
+
+// ParseBatch parses batch file lines. Invalid lines are returned with Err set,
+// so they can be reported together with the download results.
+func ParseBatch(r io.Reader) ([]BatchEntry, error) {
+	var entries []BatchEntry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields, err := splitBatchLine(scanner.Text())
+		if err == nil && len(fields) == 0 {
+			continue
+		}
+
+		entry := BatchEntry{Line: line}
+		if err == nil {
+			entry, err = parseBatchEntry(line, fields)
+		}
+		if err != nil {
+			entry.Err = fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+	return entries, nil
+}
+
+func parseBatchEntry(line int, fields []string) (BatchEntry, error) {
+	entry := BatchEntry{Line: line, ID: fields[0]}
+
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return entry, fmt.Errorf("expected key=value, got %q", field)
+		}
+		switch key {
+		case "filename":
+			entry.Filename = value
+		case "subdir":
+			entry.Subdir = value
+		default:
+			return entry, fmt.Errorf("unknown override %q, must be filename or subdir", key)
+		}
+		if !filepath.IsLocal(value) {
+			return entry, fmt.Errorf("%s must be a relative path inside the output directory", key)
+		}
+	}
+	return entry, nil
+}
+
+// splitBatchLine splits a line at whitespace. Double quotes group a value with
+// spaces, and a # outside of quotes starts a comment.
+func splitBatchLine(line string) ([]string, error) {
+	var (
+		fields  []string
+		current strings.Builder
+		inField bool
+		quoted  bool
+	)
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case quoted:
+			current.WriteRune(r)
+		case r == '#' && !inField:
+			return fields, nil
+		case r == ' ' || r == '\t' || r == '\r':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,72 @@
+package media
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadBatchFile(t *testing.T) {
+	content := strings.Join([]string{
+		"# lectures of the spring semester",
+		"",
+		"abc123",
+		"   ",
+		"https://tube.switch.ch/videos/def456  # intro",
+		"\tghi789\tfilename=\"Lecture 01\" subdir=week1\r",
+		"/channels/xyz subdir=\"channel #1\"",
+		"  # indented comment",
+		"jkl012 subdir=../outside",
+		"mno345 title=x",
+		"pqr678 filename",
+		"stu901 filename=\"unterminated",
+		"vwx234",
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "batch.txt")
+	if err := os.WriteFile(path, []byte(content), DefaultFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadBatchFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		entry   BatchEntry
+		invalid bool
+	}{
+		{BatchEntry{Line: 3, ID: "abc123"}, false},
+		{BatchEntry{Line: 5, ID: "https://tube.switch.ch/videos/def456"}, false},
+		{BatchEntry{Line: 6, ID: "ghi789", Filename: "Lecture 01", Subdir: "week1"}, false},
+		{BatchEntry{Line: 7, ID: "/channels/xyz", Subdir: "channel #1"}, false},
+		{BatchEntry{Line: 9}, true},
+		{BatchEntry{Line: 10}, true},
+		{BatchEntry{Line: 11}, true},
+		{BatchEntry{Line: 12}, true},
+		{BatchEntry{Line: 13, ID: "vwx234"}, false},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		got := entries[i]
+		if w.invalid {
+			if got.Line != w.entry.Line || got.Err == nil || !strings.HasPrefix(got.Err.Error(), "line ") {
+				t.Errorf("entry %d is %+v, want an error for line %d", i, got, w.entry.Line)
+			}
+			continue
+		}
+		if got != w.entry {
+			t.Errorf("entry %d is %+v, want %+v", i, got, w.entry)
+		}
+	}
+}
+
+func TestReadBatchFileMissing(t *testing.T) {
+	_, err := ReadBatchFile(filepath.Join(t.TempDir(), "missing.txt"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -3,23 +3,82 @@ package media
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+var errResumeMismatch = errors.New("partial download does not match the remote file")
+
+const maxRedirects = 10 // same as the default policy of http.Client
+
 type Client struct {
 	BaseURL     string
 	AccessToken string
 	Client      *http.Client
+	Retry       RetryPolicy
+	Logger      *slog.Logger // Verbose diagnostics, discarded by default
+	Out         io.Writer    // Human readable messages, progress bars and prompts
+	Events      *EventWriter // Machine readable download events, nil unless JSON output is requested
+	// TokenUsed is called after each successful API request, possibly
+	// concurrently, e.g. to track when the token was last used. May be nil.
+	TokenUsed func()
 }
 
 func NewClient(accessToken string) *Client {
-	return &Client{
+	c := &Client{
 		BaseURL:     SwitchTubeBaseURL,
 		AccessToken: accessToken,
-		Client:      &http.Client{},
+		Retry:       DefaultRetryPolicy(),
+		Logger:      slog.New(slog.DiscardHandler),
+		Out:         os.Stdout,
 	}
+	c.Client = &http.Client{CheckRedirect: c.checkRedirect}
+	return c
+}
+
+// checkRedirect follows redirects, e.g. of downloads to a CDN, like the
+// default policy, but refuses to continue over plain HTTP after HTTPS.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if via[0].URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect from HTTPS to insecure URL %s", req.URL.Redacted())
+	}
+	c.Logger.DebugContext(req.Context(), "following redirect", "host", req.URL.Host)
+	return nil
+}
+
+// resolveURL returns the address of a path the API returned, e.g. of a
+// variant. Paths are relative to the base URL, also if they start with a
+// slash, as with SwitchTube behind a path of a mirror. Absolute URLs, e.g.
+// of a CDN, are used as they are.
+func (c *Client) resolveURL(path string) (string, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", path, err)
+	}
+	base, err := url.Parse(c.BaseURL + "/")
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", c.BaseURL, err)
+	}
+	if !ref.IsAbs() && ref.Host == "" {
+		ref.Path = strings.TrimPrefix(ref.Path, "/")
+		ref.RawPath = strings.TrimPrefix(ref.RawPath, "/")
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// videoPageURL returns the address of the video on the SwitchTube website.
+func (c *Client) videoPageURL(videoID string) string {
+	return fmt.Sprintf("%s/videos/%s", c.BaseURL, videoID)
 }
 
 func (c *Client) ValidateToken(ctx context.Context) error {
@@ -42,14 +101,13 @@ func (c *Client) ValidateToken(ctx context.Context) error {
 		}
 	}()
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return nil
-	case http.StatusUnauthorized, http.StatusForbidden:
-		return fmt.Errorf("access token is invalid or expired (HTTP %d)", resp.StatusCode)
-	default:
-		return fmt.Errorf("unexpected API response: HTTP %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError(resp)
+		apiErr.tokenCheck = true
+		return apiErr
 	}
+	c.tokenUsed()
+	return nil
 }
 
 func (c *Client) fetchVideoDetails(ctx context.Context, videoID string) (*VideoDetails, error) {
@@ -73,18 +131,89 @@ func (c *Client) fetchVideoVariants(
 	return variants, nil
 }
 
+// downloadFileFromURL downloads into a .part file next to outputFile and renames
+// it once complete and flushed to disk. An existing .part file is continued with a Range request.
+// With more than one segment, byte ranges are fetched in parallel if the server supports it.
+// It returns the number of attempts needed, transient failures are retried from where they stopped.
+// Unless disabled, the MP4 structure and duration are verified before the file is renamed.
 func (c *Client) downloadFileFromURL(
 	ctx context.Context,
+	p *downloadProgress,
+	downloadURL string,
+	job *downloadJob,
+) (int, error) {
+	outputFile := job.outputFile
+	var expectedSize int64
+	fetchSingle := func() error {
+		var err error
+		expectedSize, err = c.fetchToPartFile(ctx, p, downloadURL, outputFile)
+		return err
+	}
+
+	attempts, err := 0, errSegmentedUnavailable
+	if job.segments > 1 {
+		attempts, err = c.fetchSegmented(ctx, p, downloadURL, outputFile, job.segments)
+	}
+	if errors.Is(err, errSegmentedUnavailable) {
+		attempts, err = c.withRetry(ctx, "download", fetchSingle)
+	}
+	if errors.Is(err, errResumeMismatch) {
+		fmt.Fprintln(p, "Remote file changed since the last attempt. Restarting download from the beginning.")
+		if err = removePartialDownload(outputFile); err != nil {
+			return attempts, err
+		}
+		var restartAttempts int
+		restartAttempts, err = c.withRetry(ctx, "download", fetchSingle)
+		attempts += restartAttempts
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			c.keepInterruptedDownload(p, outputFile)
+		}
+		return attempts, err
+	}
+
+	if err = commitPartialDownload(outputFile, expectedSize, job.verifier()); err != nil {
+		return attempts, err
+	}
+	fmt.Fprintf(p, "Video \"%s\" downloaded successfully \n", outputFile)
+	return attempts, nil
+}
+
+// keepInterruptedDownload keeps the .part file of a cancelled download if it
+// can be resumed, and removes it otherwise.
+func (c *Client) keepInterruptedDownload(p *downloadProgress, outputFile string) {
+	if offset, _ := loadPartialDownload(outputFile); offset > 0 {
+		fmt.Fprintf(p, "Download of \"%s\" interrupted. Run the same command again to resume it.\n", filepath.Base(outputFile))
+		return
+	}
+	if err := removePartialDownload(outputFile); err != nil {
+		fmt.Fprintf(p, "Warning: %v\n", err)
+	}
+}
+
+// fetchToPartFile downloads into the .part file, continuing it if possible.
+// It returns the expected size of the complete file, 0 if unknown.
+func (c *Client) fetchToPartFile(
+	ctx context.Context,
+	p *downloadProgress,
 	downloadURL, outputFile string,
-) (err error) {
+) (_ int64, err error) {
+	offset, partial := loadPartialDownload(outputFile)
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", partial.ifRangeValidator())
 	}
 
+	c.Logger.DebugContext(ctx, "download request", "url", downloadURL, "offset", offset)
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to download video: %w", err)
+		return 0, fmt.Errorf("failed to download video: %w", err)
 	}
 	defer func() {
 		if cerr := resp.Body.Close(); cerr != nil && err == nil {
@@ -92,13 +221,16 @@ func (c *Client) downloadFileFromURL(
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code for download: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+		if offset == partial.TotalSize { // the previous attempt already got everything
+			return partial.TotalSize, nil
+		}
+		return 0, errResumeMismatch
 	}
 
-	out, err := os.Create(outputFile)
+	out, offset, totalSize, err := openPartFile(resp, outputFile, offset, partial)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer func() {
 		if cerr := out.Close(); cerr != nil && err == nil {
@@ -106,7 +238,57 @@ func (c *Client) downloadFileFromURL(
 		}
 	}()
 
-	return copyWithProgress(ctx, resp, out)
+	if offset > 0 {
+		fmt.Fprintf(p, "Resuming download at %d of %d bytes\n", offset, totalSize)
+	}
+	return totalSize, copyWithProgress(p, filepath.Base(outputFile), resp.Body, out, offset, totalSize)
+}
+
+// openPartFile checks the download response and opens the .part file for
+// writing. It returns the offset at which the response body starts and the
+// total size of the file (0 if unknown).
+func openPartFile(
+	resp *http.Response,
+	outputFile string,
+	offset int64,
+	partial *partialDownload,
+) (*os.File, int64, int64, error) {
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if offset == 0 {
+			return nil, 0, 0, errors.New("server sent partial content for a full download request")
+		}
+		start, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if start != offset || !partial.matches(resp, total) {
+			return nil, 0, 0, errResumeMismatch
+		}
+		if total < 0 {
+			total = partial.TotalSize
+		}
+		out, err := os.OpenFile(partPath(outputFile), os.O_WRONLY|os.O_APPEND, DefaultFilePermissions)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to open partial file: %w", err)
+		}
+		return out, offset, total, nil
+
+	case http.StatusOK: // full body, either a fresh download or the server ignored the range
+		totalSize := max(resp.ContentLength, 0)
+		out, err := os.Create(partPath(outputFile))
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to create output file: %w", err)
+		}
+		if err = savePartialDownload(outputFile, partialFromResponse(resp, totalSize)); err != nil {
+			_ = out.Close()
+			return nil, 0, 0, err
+		}
+		return out, 0, totalSize, nil
+
+	default:
+		return nil, 0, 0, fmt.Errorf("download request failed: %w", newAPIError(resp))
+	}
 }
 
 func (c *Client) fetchChannelDetails(
@@ -123,24 +305,42 @@ func (c *Client) fetchChannelDetails(
 
 func (c *Client) fetchChannelVideos(ctx context.Context, channelID string) ([]ChannelVideo, error) {
 	url := fmt.Sprintf("%s/api/v1/browse/channels/%s/videos", c.BaseURL, channelID)
-	var videos []ChannelVideo
-	if err := c.getJSON(ctx, url, &videos); err != nil {
+	videos, err := collectPages[ChannelVideo](ctx, c, url)
+	if err != nil {
 		return nil, fmt.Errorf("fetch channel videos failed: %w", err)
 	}
 	return videos, nil
 }
 
 func (c *Client) getJSON(ctx context.Context, url string, target any) error {
+	_, err := c.getJSONWithHeader(ctx, url, target)
+	return err
+}
+
+// getJSONWithHeader is getJSON that also returns the response headers, e.g. for pagination links.
+// Transient failures are retried according to the client's retry policy.
+func (c *Client) getJSONWithHeader(ctx context.Context, url string, target any) (http.Header, error) {
+	var header http.Header
+	_, err := c.withRetry(ctx, "GET "+url, func() error {
+		var attemptErr error
+		header, attemptErr = c.fetchJSON(ctx, url, target)
+		return attemptErr
+	})
+	return header, err
+}
+
+func (c *Client) fetchJSON(ctx context.Context, url string, target any) (_ http.Header, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.AccessToken))
 	req.Header.Set("Accept", "application/json")
 
+	c.Logger.DebugContext(ctx, "API request", "url", url)
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return nil, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer func() {
 		if cerr := resp.Body.Close(); cerr != nil && err == nil {
@@ -149,11 +349,18 @@ func (c *Client) getJSON(ctx context.Context, url string, target any) error {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newAPIError(resp)
 	}
 
 	if err = json.NewDecoder(resp.Body).Decode(target); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	c.tokenUsed()
+	return resp.Header, nil
+}
+
+func (c *Client) tokenUsed() {
+	if c.TokenUsed != nil {
+		c.TokenUsed()
 	}
-	return nil
 }
@@ -0,0 +1,108 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize limits how much of an error response is kept in APIError.
+const maxErrorBodySize = 200
+
+// Categories of API errors, use errors.Is to check an error against them.
+var (
+	ErrUnauthorized = errors.New("access token is invalid or expired")
+	ErrNotFound     = errors.New("not found")
+	ErrForbidden    = fmt.Errorf("%w or not accessible", ErrNotFound) // Also matches ErrNotFound
+	ErrRateLimited  = errors.New("rate limited by the server")
+)
+
+// APIError is returned for responses with an unexpected HTTP status.
+type APIError struct {
+	StatusCode int
+	URL        string
+	Body       string        // Beginning of the response body, if any
+	RetryAfter time.Duration // Parsed Retry-After header, 0 if absent
+
+	tokenCheck bool // Response to ValidateToken, where HTTP 403 means the token was rejected
+}
+
+// newAPIError reads the beginning of the response body. The caller still closes it.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		u := *resp.Request.URL
+		u.RawQuery = "" // may contain signatures of media URLs
+		apiErr.URL = u.String()
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	apiErr.Body = strings.ToValidUTF8(strings.Join(strings.Fields(string(body)), " "), "")
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	status := fmt.Sprintf("HTTP %d", e.StatusCode)
+	if e.URL != "" {
+		status += " from " + e.URL
+	}
+	msg := fmt.Sprintf("unexpected status code (%s)", status)
+	if category := e.category(); category != nil {
+		msg = fmt.Sprintf("%v (%s)", category, status)
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Is reports whether the status belongs to one of the error categories.
+func (e *APIError) Is(target error) bool {
+	category := e.category()
+	return category != nil && errors.Is(category, target)
+}
+
+func (e *APIError) category() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		// Other resources answer 403 to valid tokens without access to them
+		if e.tokenCheck {
+			return ErrUnauthorized
+		}
+		return ErrForbidden
+	case http.StatusNotFound, http.StatusGone:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return nil
+	}
+}
+
+// IsNetworkError reports whether err was caused by the network, e.g. a failed
+// DNS lookup, a refused connection or a timeout, rather than by the server.
+// Rejected certificates and refused redirects are not network errors.
+func IsNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// Not net.Error alone, which syscall.Errno implements for local failures as well
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op != "remote error" // a TLS alert sent by the server
+	}
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	return errors.As(err, &dnsErr) || errors.As(err, &netErr) && netErr.Timeout() ||
+		errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,91 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsNetworkError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", transportError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"unknown host", transportError(&net.OpError{Op: "dial", Err: &net.DNSError{Name: "tube.swich.ch", IsNotFound: true}}), true},
+		{"DNS error", &net.DNSError{Name: "tube.switch.ch", IsTemporary: true}, true},
+		{"timeout", transportError(fmt.Errorf("reading body: %w", os.ErrDeadlineExceeded)), true},
+		{"TLS alert", transportError(&net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")}), false},
+		{"status", &APIError{StatusCode: http.StatusNotFound}, false},
+		{"local file", &os.PathError{Op: "open", Path: "video.mp4", Err: syscall.ENOSPC}, false},
+		{"canceled", transportError(context.Canceled), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNetworkError(tt.err); got != tt.want {
+				t.Errorf("IsNetworkError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNetworkErrorWithClient(t *testing.T) {
+	insecure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer insecure.Close()
+	secure := httptest.NewTLSServer(http.RedirectHandler(insecure.URL+"/api/v1/profiles/me", http.StatusFound))
+	defer secure.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	trusting := NewClient("token")
+	trusting.Client.Transport = secure.Client().Transport // trusts the test certificate
+	tests := []struct {
+		name    string
+		client  *Client
+		baseURL string
+		want    bool
+	}{
+		{"refused redirect", trusting, secure.URL, false},
+		{"unknown certificate", NewClient("token"), secure.URL, false},
+		{"connection refused", NewClient("token"), closed.URL, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.client.BaseURL = tt.baseURL
+			err := tt.client.ValidateToken(context.Background())
+			if err == nil {
+				t.Fatal("request succeeded")
+			}
+			if got := IsNetworkError(err); got != tt.want {
+				t.Errorf("IsNetworkError(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorForbidden(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	c := NewClient("token")
+	c.BaseURL = srv.URL
+
+	// A token check is only refused for the token, resources may be restricted to other accounts
+	if err := c.ValidateToken(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("token check: got %v, want %v", err, ErrUnauthorized)
+	}
+	_, err := c.fetchVideoDetails(context.Background(), "abc")
+	if !errors.Is(err, ErrForbidden) || !errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("video: got %v, want %v", err, ErrForbidden)
+	}
+}
@@ -0,0 +1,134 @@
+package media
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressEventInterval limits how often progress events are written per download.
+const progressEventInterval = time.Second
+
+type EventType string
+
+const (
+	EventStarted  EventType = "started"
+	EventProgress EventType = "progress"
+	EventSkipped  EventType = "skipped"
+	EventFinished EventType = "finished"
+	EventFailed   EventType = "failed"
+	EventSummary  EventType = "summary"
+)
+
+// Event is a single JSON line describing the state of a video download.
+type Event struct {
+	Type       EventType `json:"type"`
+	Time       time.Time `json:"time"`
+	VideoID    string    `json:"video_id"`
+	Title      string    `json:"title,omitempty"`
+	Variant    string    `json:"variant,omitempty"`
+	OutputFile string    `json:"output_file,omitempty"`
+	Bytes      int64     `json:"bytes,omitempty"`       // Bytes written so far, or the file size once finished
+	TotalBytes int64     `json:"total_bytes,omitempty"` // 0 if the server did not report a size
+	Attempts   int       `json:"attempts,omitempty"`
+	Reason     string    `json:"reason,omitempty"` // Why a video was skipped
+	Error      string    `json:"error,omitempty"`
+}
+
+// EventWriter writes events as JSON lines. It is safe for concurrent use, and
+// a nil EventWriter discards all events, so callers don't need to check the output mode.
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *EventWriter) Emit(event Event) {
+	if w == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	w.encode(event)
+}
+
+// WriteSummary writes the summary as the final line of a download.
+func (w *EventWriter) WriteSummary(summary *DownloadSummary) {
+	if w == nil {
+		return
+	}
+	w.encode(struct {
+		Type EventType `json:"type"`
+		*DownloadSummary
+	}{EventSummary, summary})
+}
+
+// encode ignores write errors, the events are the only output that could report them.
+func (w *EventWriter) encode(v any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(v)
+}
+
+// transferEvents reports the bytes written for one video as progress events.
+type transferEvents struct {
+	events     *EventWriter
+	videoID    string
+	outputFile string
+	written    atomic.Int64
+	total      atomic.Int64
+	lastEmit   atomic.Int64 // Unix nanoseconds of the last progress event
+}
+
+// start resets the counters for a new attempt that continues at offset.
+func (t *transferEvents) start(offset, totalSize int64) {
+	if t == nil {
+		return
+	}
+	t.written.Store(offset)
+	t.total.Store(totalSize)
+}
+
+func (t *transferEvents) wrap(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &progressReader{reader: r, transfer: t}
+}
+
+func (t *transferEvents) add(n int64) {
+	written := t.written.Add(n)
+
+	now := time.Now()
+	last := t.lastEmit.Load()
+	if now.UnixNano()-last < int64(progressEventInterval) || !t.lastEmit.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	t.events.Emit(Event{
+		Type:       EventProgress,
+		Time:       now.UTC(),
+		VideoID:    t.videoID,
+		OutputFile: t.outputFile,
+		Bytes:      written,
+		TotalBytes: t.total.Load(),
+	})
+}
+
+type progressReader struct {
+	reader   io.Reader
+	transfer *transferEvents
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.transfer.add(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,238 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultMediaType is the media type preferred by formats without a type filter.
+const defaultMediaType = "video/mp4"
+
+// FormatSelector picks a variant by a preference expression like "720p/best".
+// Alternatives are separated by "/" and tried from left to right, the first one
+// matching any variant wins. Each alternative is one of
+//
+//	best, worst       the highest or lowest quality variant
+//	audio             the best audio-only variant (media type audio/*)
+//	<name>            the variant with this name, e.g. 720p
+//
+// optionally followed by filters in brackets, e.g. "worst[name~=1080]". A filter
+// alone ("name~=1080", "[type=video/webm]") selects the best matching variant.
+// Filters compare the key name or type (media type) with =, !=, ~= (contains),
+// ^= (starts with) or $= (ends with), ignoring case. Alternatives without a
+// type filter prefer video/mp4 variants, other media types are only selected
+// if no alternative matches a video/mp4 variant.
+type FormatSelector struct {
+	expr         string
+	alternatives []formatAlternative
+}
+
+type formatAlternative struct {
+	worst   bool
+	filters []formatFilter
+}
+
+type formatFilter struct {
+	key   string // "name" or "type"
+	op    string
+	value string
+}
+
+// formatOps are the filter operators, two-character ones first so that "!=" isn't read as "=".
+var formatOps = []string{"!=", "~=", "^=", "$=", "="}
+
+// ParseFormat parses a format expression. An empty expression selects the best variant.
+func ParseFormat(expr string) (*FormatSelector, error) {
+	selector := &FormatSelector{expr: expr}
+	if strings.TrimSpace(expr) == "" {
+		selector.expr = "best"
+		selector.alternatives = []formatAlternative{{}}
+		return selector, nil
+	}
+
+	for _, part := range splitFormat(expr) {
+		alternative, err := parseFormatAlternative(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid format %q: %w", expr, err)
+		}
+		selector.alternatives = append(selector.alternatives, alternative)
+	}
+	return selector, nil
+}
+
+// mediaTypeMajors are the top-level media types, a slash following one of them
+// in a bare type filter continues the media type.
+var mediaTypeMajors = []string{"application", "audio", "image", "text", "video"}
+
+// splitFormat splits an expression into its alternatives at "/". Slashes inside
+// brackets and the one after the top-level type in a bare type filter
+// ("type=video/mp4") don't separate alternatives.
+func splitFormat(expr string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range expr {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '/':
+			current := strings.TrimSpace(expr[start:i])
+			if depth > 0 || endsWithTopLevelType(current) {
+				continue
+			}
+			parts = append(parts, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// endsWithTopLevelType reports whether s is a bare type filter whose value is
+// a top-level media type only, e.g. "type=video" but not "type~=webm".
+func endsWithTopLevelType(s string) bool {
+	filter, err := parseFormatFilter(s)
+	return err == nil && filter.key == "type" && !strings.ContainsAny(s, "[]") &&
+		slices.Contains(mediaTypeMajors, strings.ToLower(filter.value))
+}
+
+func parseFormatAlternative(s string) (formatAlternative, error) {
+	var alternative formatAlternative
+	if s == "" {
+		return alternative, errors.New("empty alternative")
+	}
+
+	base, rest := s, ""
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		base, rest = strings.TrimSpace(s[:i]), s[i:]
+	}
+
+	switch {
+	case base == "" || base == "best":
+	case base == "worst":
+		alternative.worst = true
+	case base == "audio":
+		alternative.filters = append(alternative.filters, formatFilter{key: "type", op: "^=", value: "audio/"})
+	case strings.ContainsAny(base, "=]"):
+		filter, err := parseFormatFilter(base)
+		if err != nil {
+			return alternative, err
+		}
+		alternative.filters = append(alternative.filters, filter)
+	default:
+		alternative.filters = append(alternative.filters, formatFilter{key: "name", op: "=", value: base})
+	}
+
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if !strings.HasPrefix(rest, "[") || end < 0 {
+			return alternative, fmt.Errorf("unbalanced brackets in %q", s)
+		}
+		filter, err := parseFormatFilter(rest[1:end])
+		if err != nil {
+			return alternative, err
+		}
+		alternative.filters = append(alternative.filters, filter)
+		rest = strings.TrimSpace(rest[end+1:])
+	}
+	return alternative, nil
+}
+
+func parseFormatFilter(s string) (formatFilter, error) {
+	for _, op := range formatOps {
+		key, value, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+		filter := formatFilter{key: strings.ToLower(strings.TrimSpace(key)), op: op, value: strings.TrimSpace(value)}
+		switch filter.key {
+		case "name", "type":
+		case "media_type":
+			filter.key = "type"
+		default:
+			return filter, fmt.Errorf("unknown filter key %q, must be name or type", filter.key)
+		}
+		if filter.value == "" {
+			return filter, fmt.Errorf("filter %q has no value", s)
+		}
+		return filter, nil
+	}
+	return formatFilter{}, fmt.Errorf("filter %q has no operator (=, !=, ~=, ^=, $=)", s)
+}
+
+// String returns the expression the selector was parsed from.
+func (f *FormatSelector) String() string {
+	if f == nil {
+		return "best"
+	}
+	return f.expr
+}
+
+// Select returns the variant chosen by the first matching alternative.
+// Variants must be ordered from highest to lowest quality, as returned by the API.
+// A nil selector selects the best variant.
+func (f *FormatSelector) Select(variants []VideoVariant) (*VideoVariant, error) {
+	alternatives := []formatAlternative{{}}
+	if f != nil {
+		alternatives = f.alternatives
+	}
+
+	// NOTE: from the api docs "Video variants are ordered on their quality level with the highest quality variant first."
+	// Without a type filter video/mp4 is preferred, other types are only used if no alternative matches a video/mp4 variant
+	for _, anyType := range []bool{false, true} {
+		for _, alternative := range alternatives {
+			var matching []int
+			for i := range variants {
+				if alternative.matches(&variants[i], anyType) {
+					matching = append(matching, i)
+				}
+			}
+			if len(matching) == 0 {
+				continue
+			}
+			if alternative.worst {
+				return &variants[matching[len(matching)-1]], nil
+			}
+			return &variants[matching[0]], nil
+		}
+	}
+	return nil, fmt.Errorf("no variant matches format %q", f.String())
+}
+
+func (a formatAlternative) matches(variant *VideoVariant, anyType bool) bool {
+	hasTypeFilter := false
+	for _, filter := range a.filters {
+		if filter.key == "type" {
+			hasTypeFilter = true
+		}
+		if !filter.matches(variant) {
+			return false
+		}
+	}
+	return anyType || hasTypeFilter || baseMediaType(variant.MediaType) == defaultMediaType
+}
+
+func (f formatFilter) matches(variant *VideoVariant) bool {
+	actual := variant.Name
+	if f.key == "type" {
+		actual = variant.MediaType
+	}
+	actual, value := strings.ToLower(actual), strings.ToLower(f.value)
+
+	switch f.op {
+	case "=":
+		return actual == value
+	case "!=":
+		return actual != value
+	case "~=":
+		return strings.Contains(actual, value)
+	case "^=":
+		return strings.HasPrefix(actual, value)
+	case "$=":
+		return strings.HasSuffix(actual, value)
+	default:
+		return false
+	}
+}
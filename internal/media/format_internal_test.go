@@ -0,0 +1,133 @@
+package media
+
+import "testing"
+
+var testVariants = []VideoVariant{
+	{Name: "1080p", MediaType: "video/mp4"},
+	{Name: "1080p-webm", MediaType: "video/webm"},
+	{Name: "720p", MediaType: "video/mp4"},
+	{Name: "audio", MediaType: "audio/mp4"},
+	{Name: "360p", MediaType: "video/mp4"},
+}
+
+func TestFormatSelectorSelect(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // name of the selected variant, empty if none matches
+	}{
+		{"", "1080p"},
+		{"best", "1080p"},
+		{"worst", "360p"},
+		{"720p/best", "720p"},
+		{"480p/worst", "360p"},
+		{"name~=1080", "1080p"},
+		{"worst[name~=1080]", "1080p"},
+		{"worst[name!=360p]", "720p"},
+		{"[type=video/webm]/best", "1080p-webm"},
+		{"type=video/webm/best", "1080p-webm"},
+		{"type=video/ogg/best", "1080p"},
+		{"type~=webm/best", "1080p-webm"},
+		{"type~=ogg/worst", "360p"},
+		{"audio", "audio"},
+		{"audio[name=none]/720p", "720p"},
+		{"1440p", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			selector, err := ParseFormat(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := selector.Select(testVariants)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("selected %q, want no match", got.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("selected %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSelectorPrefersMP4(t *testing.T) {
+	noMP4 := []VideoVariant{
+		{Name: "1080p", MediaType: "video/webm"},
+		{Name: "720p", MediaType: "video/webm"},
+	}
+	mixed := []VideoVariant{
+		{Name: "1080p", MediaType: "video/webm"},
+		{Name: "720p", MediaType: "video/mp4"},
+		{Name: "360p", MediaType: "video/webm"},
+	}
+	tests := []struct {
+		expr     string
+		variants []VideoVariant
+		want     string
+	}{
+		{"best", noMP4, "1080p"},
+		{"worst", noMP4, "720p"},
+		{"best", mixed, "720p"},
+		{"worst", mixed, "720p"},
+		{"360p/best", mixed, "720p"}, // an MP4 alternative wins over an earlier one of another type
+		{"360p/1080p", mixed, "360p"},
+		{"[type=video/mp4]", noMP4, ""}, // explicit types don't fall back
+	}
+	for _, tt := range tests {
+		selector, err := ParseFormat(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := selector.Select(tt.variants)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("%q selected %q, want no match", tt.expr, got.Name)
+		case tt.want != "" && err != nil:
+			t.Errorf("%q: %v", tt.expr, err)
+		case tt.want != "" && got.Name != tt.want:
+			t.Errorf("%q selected %q, want %q", tt.expr, got.Name, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		expr         string
+		alternatives int
+		wantErr      bool
+	}{
+		{"type=video/webm/best", 2, false},
+		{"type~=webm/best", 2, false},
+		{"TYPE=Audio/MP4/worst", 2, false},
+		{"[type=video/webm]/best", 2, false},
+		{"worst[name~=1080]", 1, false},
+		{"name~=1080", 1, false},
+		{"audio", 1, false},
+		{"720p//best", 0, true},
+		{"worst[name~=1080", 0, true},
+		{"[size=1080]", 0, true},
+		{"name=", 0, true},
+		{"[name]", 0, true},
+	}
+	for _, tt := range tests {
+		selector, err := ParseFormat(tt.expr)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %d alternatives, want an error", tt.expr, len(selector.alternatives))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if len(selector.alternatives) != tt.alternatives {
+			t.Errorf("%q: got %d alternatives, want %d", tt.expr, len(selector.alternatives), tt.alternatives)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// VideoInfo is the metadata of a video and its downloadable variants.
+type VideoInfo struct {
+	VideoDetails
+	Variants []VideoVariant `json:"variants"`
+}
+
+// ChannelInfo is the metadata of a channel and all of its videos.
+type ChannelInfo struct {
+	ChannelDetails
+	Videos []*VideoDetails `json:"videos"`
+}
+
+func (c *Client) FetchVideoInfo(ctx context.Context, videoID string) (*VideoInfo, error) {
+	details, err := c.fetchVideoDetails(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := c.fetchVideoVariants(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	return &VideoInfo{VideoDetails: *details, Variants: variants}, nil
+}
+
+// FetchChannelInfo fetches the details of every video in the channel, which
+// takes one request per video.
+func (c *Client) FetchChannelInfo(ctx context.Context, channelID string) (*ChannelInfo, error) {
+	details, err := c.fetchChannelDetails(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	channelVideos, err := c.fetchChannelVideos(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ChannelInfo{ChannelDetails: *details, Videos: make([]*VideoDetails, len(channelVideos))}
+	for i, v := range channelVideos {
+		info.Videos[i], err = c.fetchVideoDetails(ctx, v.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch video details for %s: %w", v.ID, err)
+		}
+	}
+	return info, nil
+}
+
+func (c *Client) PrintVideoInfo(info *VideoInfo) error {
+	duration, date := formatVideoDetails(&info.VideoDetails)
+	fmt.Fprintf(c.Out, "Title:     %s\n", info.Title)
+	fmt.Fprintf(c.Out, "ID:        %s\n", info.ID)
+	fmt.Fprintf(c.Out, "Published: %s\n", date)
+	fmt.Fprintf(c.Out, "Duration:  %s\n", duration)
+
+	if len(info.Variants) == 0 {
+		fmt.Fprintln(c.Out, "\nNo variants available.")
+		return nil
+	}
+	fmt.Fprintln(c.Out, "\nVariants:")
+
+	const (
+		minWidth = 0
+		tabWidth = 0
+		padding  = 3
+		padChar  = ' '
+		flags    = 0
+	)
+	writer := tabwriter.NewWriter(c.Out, minWidth, tabWidth, padding, padChar, flags)
+	if _, err := fmt.Fprintln(writer, "Index\tName\tMedia type\tExpires"); err != nil {
+		return fmt.Errorf("failed to write table header: %w", err)
+	}
+	for i, v := range info.Variants {
+		if _, err := fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", i+1, v.Name, v.MediaType, formatExpiry(v.ExpiresAt)); err != nil {
+			return fmt.Errorf("failed to write variant row %d: %w", i+1, err)
+		}
+	}
+	return writer.Flush()
+}
+
+func (c *Client) PrintChannelInfo(info *ChannelInfo) error {
+	fmt.Fprintf(c.Out, "Channel: %s\n", info.Name)
+	fmt.Fprintf(c.Out, "ID:      %s\n", info.ID)
+	fmt.Fprintf(c.Out, "Videos:  %d\n", len(info.Videos))
+	if len(info.Videos) == 0 {
+		return nil
+	}
+	return c.displayVideosInTable(info.Videos)
+}
+
+func formatExpiry(expiresAt string) string {
+	if expiresAt == "" {
+		return "-"
+	}
+	parsedTime, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return expiresAt
+	}
+	return parsedTime.Local().Format(time.DateTime)
+}
@@ -0,0 +1,199 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vbauerster/mpb/v8"
+)
+
+// downloadJob is a fully prepared download that no longer needs user input.
+type downloadJob struct {
+	index      int
+	videoID    string
+	details    *VideoDetails
+	variant    *VideoVariant
+	outputFile string
+	audioFile  string // where the audio track is extracted to
+	segments   int
+	verify     bool // check the MP4 structure and duration before renaming the download
+	archive    *DownloadArchive
+	channel    string // name of the channel, if known
+
+	writeInfoJSON bool
+	writeNFO      bool
+	embedMetadata bool
+	extractAudio  bool   // write the audio track to an .m4a file after downloading
+	deleteVideo   bool   // delete the video once its audio was extracted
+	mediaType     string // of the downloaded variant, set once it is resolved
+	skip          bool   // the output file exists and the user chose to keep it
+	attempts      int    // download attempts, set once the job ran
+	size          int64  // size of the downloaded file, set once the job succeeded
+}
+
+// verifier returns the integrity check of the downloaded file, nil if disabled
+// or if the variant isn't an MP4 file.
+func (job *downloadJob) verifier() func(path string) error {
+	if !job.verify || !isMP4MediaType(job.mediaType) {
+		return nil
+	}
+	expected := time.Duration(job.details.DurationInMilliseconds) * time.Millisecond
+	return func(path string) error {
+		_, err := VerifyMP4(path, expected)
+		return err
+	}
+}
+
+// downloadProgress is the progress container shared by concurrent downloads.
+// Messages written to it are printed above the running bars.
+type downloadProgress struct {
+	container *mpb.Progress
+	out       io.Writer
+	terminal  bool
+	transfer  *transferEvents // Progress events of a single job, see forJob
+}
+
+func (c *Client) newDownloadProgress(ctx context.Context) *downloadProgress {
+	return &downloadProgress{
+		container: mpb.NewWithContext(ctx, mpb.WithWidth(progressBarWidth), mpb.WithOutput(c.Out)),
+		out:       c.Out,
+		terminal:  isTerminal(c.Out),
+	}
+}
+
+// forJob returns a view of the container that also reports the progress of
+// the job as events, if the client emits events.
+func (p *downloadProgress) forJob(events *EventWriter, job *downloadJob) *downloadProgress {
+	if events == nil {
+		return p
+	}
+	jobProgress := *p
+	jobProgress.transfer = &transferEvents{events: events, videoID: job.videoID, outputFile: job.outputFile}
+	return &jobProgress
+}
+
+// Write prints above the bars. The container only flushes intercepted output
+// when it renders, which it never does if the output is not a terminal.
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	if !p.terminal {
+		return p.out.Write(b)
+	}
+	return p.container.Write(b)
+}
+
+// downloadBar is a progress bar that also reports the bytes read through it as events.
+type downloadBar struct {
+	*mpb.Bar
+	transfer *transferEvents
+}
+
+func (b *downloadBar) ProxyReader(r io.Reader) io.ReadCloser {
+	return b.Bar.ProxyReader(b.transfer.wrap(r))
+}
+
+// runDownloadJobs downloads the jobs with the given number of workers, sharing
+// one progress container. The returned errors are in the order of jobs.
+func (c *Client) runDownloadJobs(ctx context.Context, jobs []*downloadJob, workers int) []error {
+	errs := make([]error, len(jobs))
+	if len(jobs) == 0 {
+		return errs
+	}
+	workers = min(max(workers, 1), len(jobs))
+
+	p := c.newDownloadProgress(ctx)
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				job := jobs[i]
+				errs[i] = c.runDownloadJob(ctx, p.forJob(c.Events, job), job)
+				if errs[i] != nil {
+					fmt.Fprintf(p, "Failed to download video %s: %v\n", job.videoID, errs[i])
+					c.Events.Emit(Event{
+						Type:       EventFailed,
+						VideoID:    job.videoID,
+						OutputFile: job.outputFile,
+						Attempts:   job.attempts,
+						Error:      errs[i].Error(),
+					})
+					continue
+				}
+				c.Events.Emit(Event{
+					Type:       EventFinished,
+					VideoID:    job.videoID,
+					OutputFile: job.outputFile,
+					Bytes:      job.size,
+					Attempts:   job.attempts,
+				})
+			}
+		}()
+	}
+
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+
+	wg.Wait()
+	p.container.Wait()
+	return errs
+}
+
+func (c *Client) runDownloadJob(ctx context.Context, p *downloadProgress, job *downloadJob) error {
+	variant := job.variant
+	job.mediaType = variant.MediaType
+
+	if err := os.MkdirAll(filepath.Dir(job.outputFile), DefaultDirectoryPermissions); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintf(p, "Downloading video \"%s\"\n", filepath.Base(job.outputFile))
+	c.Events.Emit(Event{
+		Type:       EventStarted,
+		VideoID:    job.videoID,
+		Title:      job.details.Title,
+		Variant:    variant.Name,
+		OutputFile: job.outputFile,
+	})
+	downloadURL, err := c.resolveURL(variant.Path)
+	if err != nil {
+		return fmt.Errorf("invalid variant path: %w", err)
+	}
+	attempts, err := c.downloadFileFromURL(ctx, p, downloadURL, job)
+	job.attempts = attempts
+	if err != nil {
+		return err
+	}
+
+	if job.embedMetadata && !isMP4MediaType(job.mediaType) {
+		fmt.Fprintf(p, "Not embedding metadata into \"%s\", only MP4 files are supported\n", filepath.Base(job.outputFile))
+	} else if job.embedMetadata {
+		// The file is only replaced on success, so the download is still complete and recorded
+		if err := embedMetadata(job.outputFile, c.metadataTags(job)); err != nil {
+			fmt.Fprintf(p, "Warning: failed to embed metadata into \"%s\": %v\n", filepath.Base(job.outputFile), err)
+		}
+	}
+	if job.extractAudio {
+		if err := c.extractJobAudio(p, job); err != nil {
+			return fmt.Errorf("video downloaded, but failed to extract audio: %w", err)
+		}
+	}
+	job.size = fileSize(job.outputFile)
+
+	if err := c.writeSidecars(job, variant); err != nil {
+		return fmt.Errorf("video downloaded, but %w", err)
+	}
+	if err := job.archive.Record(job.videoID, variant.Name); err != nil {
+		return fmt.Errorf("video downloaded, but %w", err)
+	}
+	return nil
+}
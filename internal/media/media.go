@@ -3,38 +3,112 @@ package media
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
 	SwitchTubeBaseURL           = "https://tube.switch.ch"
 	DefaultDirectoryPermissions = 0o755
+	DefaultFilePermissions      = 0o644
 )
 
 type DownloadConfig struct {
-	AccessToken   string
+	AccessToken   string `mapstructure:"token"`
+	TokenFile     string `mapstructure:"token-file"`    // File containing the token, read instead of the store
+	TokenCommand  string `mapstructure:"token-command"` // Shell command printing the token
 	ChannelID     string
 	VideoIDs      []string
-	OutputDir     string `mapstructure:"output-dir"`
-	Filename      string `mapstructure:"filename"`
-	Overwrite     bool   `mapstructure:"overwrite"`
-	Skip          bool   `mapstructure:"skip"`
-	SelectVariant bool   `mapstructure:"select-variant"`
-	All           bool   `mapstructure:"all"`
+	Profile       string           `mapstructure:"profile"`     // Name of the stored token and the profile settings in the config file
+	BaseURL       string           `mapstructure:"base-url"`    // SwitchTube instance, empty for SwitchTubeBaseURL
+	TokenStore    string           `mapstructure:"token-store"` // Backend of the stored tokens, see keyringconfig.OpenStore
+	OutputDir     string           `mapstructure:"output-dir"`
+	Filename      string           `mapstructure:"filename"`
+	Overwrite     bool             `mapstructure:"overwrite"`
+	Skip          bool             `mapstructure:"skip"`
+	SelectVariant bool             `mapstructure:"select-variant"`
+	Format        string           `mapstructure:"format"` // Variant preference expression, see FormatSelector
+	Selector      *FormatSelector  `mapstructure:"-"`      // Parsed from Format, nil selects the best variant
+	All           bool             `mapstructure:"all"`
+	MoveRemoved   bool             `mapstructure:"move-removed"` // Sync only: move files of removed videos to .removed/
+	Jobs          int              `mapstructure:"jobs"`         // Number of videos downloaded in parallel
+	Segments      int              `mapstructure:"segments"`     // Number of byte ranges fetched in parallel per video
+	ArchiveFile   string           `mapstructure:"download-archive"`
+	Archive       *DownloadArchive `mapstructure:"-"`               // Opened from ArchiveFile, nil if not used
+	Retries       int              `mapstructure:"retries"`         // Retries of transient request failures
+	RetryDelay    time.Duration    `mapstructure:"retry-delay"`     // Base delay of the exponential backoff
+	NoVerify      bool             `mapstructure:"no-verify"`       // Skip the MP4 integrity check of downloads
+	WriteInfoJSON bool             `mapstructure:"write-info-json"` // Write <name>.info.json next to each video
+	WriteNFO      bool             `mapstructure:"write-nfo"`       // Write <name>.nfo (Kodi/Jellyfin) next to each video
+	EmbedMetadata bool             `mapstructure:"embed-metadata"`  // Write title, channel, date and URL into the MP4 file
+	ExtractAudio  bool             `mapstructure:"extract-audio"`   // Write the audio track to <name>.m4a after downloading
+	DeleteVideo   bool             `mapstructure:"delete-video"`    // Delete the video once its audio was extracted
+	Verbose       bool             `mapstructure:"verbose"`
+	Output        string           `mapstructure:"output"` // "text" or "json"
+	// OutputTemplate names downloaded files, e.g. "{channel}/{published}_{title}"
+	OutputTemplate string                  `mapstructure:"output-template"`
+	ChannelName    string                  `mapstructure:"-"` // Set for channel downloads, used by {channel}
+	Videos         map[string]VideoOptions `mapstructure:"-"` // Optional per-video settings by video ID
+}
+
+// VideoOptions holds settings of a single video in a multi-video download.
+type VideoOptions struct {
+	Index    int    // Position in the channel, used by {index}; defaults to the position in VideoIDs
+	Filename string // Overrides the output template, e.g. from a batch file
+	Subdir   string // Subdirectory of OutputDir
 }
 
 type DownloadSummary struct {
-	Total     int
-	Succeeded int
-	Failed    int
-	Results   []DownloadResult
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"` // Includes skipped videos
+	Failed    int              `json:"failed"`
+	Skipped   int              `json:"skipped"`
+	Results   []DownloadResult `json:"results"`
+}
+
+// Merge adds the results of another download, e.g. of the next channel.
+func (s *DownloadSummary) Merge(other *DownloadSummary) {
+	s.Total += other.Total
+	s.Succeeded += other.Succeeded
+	s.Failed += other.Failed
+	s.Skipped += other.Skipped
+	s.Results = append(s.Results, other.Results...)
+}
+
+// AddFailed adds a video or channel that failed before it could be downloaded,
+// e.g. an invalid line of a batch file.
+func (s *DownloadSummary) AddFailed(result DownloadResult) {
+	s.Total++
+	s.Failed++
+	s.Results = append(s.Results, result)
 }
 
 type DownloadResult struct {
-	VideoID string
-	Error   error
+	VideoID    string `json:"video_id"`
+	ChannelID  string `json:"channel_id,omitempty"` // Set instead of VideoID if a whole channel failed
+	OutputFile string `json:"output_file,omitempty"`
+	Size       int64  `json:"size,omitempty"`     // Size of the output file in bytes
+	Skipped    bool   `json:"skipped,omitempty"`  // Already downloaded (existing file or download archive)
+	Attempts   int    `json:"attempts,omitempty"` // Number of download attempts, more than 1 if transient errors were retried
+	Error      error  `json:"-"`
+}
+
+// MarshalJSON includes the error message, which encoding/json can't derive from the error value.
+func (r DownloadResult) MarshalJSON() ([]byte, error) {
+	type plain DownloadResult
+	var errMsg string
+	if r.Error != nil {
+		errMsg = r.Error.Error()
+	}
+	return json.Marshal(struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain(r), errMsg})
 }
 
 type ChannelDetails struct {
@@ -43,15 +117,16 @@ type ChannelDetails struct {
 }
 
 type ChannelVideo struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	PublishedAt string `json:"published_at"` // Same format as VideoDetails.PublishedAt
 }
 
 type VideoVariant struct {
 	Path      string `json:"path"`
 	Name      string `json:"name"`       // Label to distinguish variants, not display title
-	MediaType string `json:"media_type"` // Expected to be video/mp4 for video downloads
-	ExpiresAt string `json:"expires_at"` // NOTE: not used
+	MediaType string `json:"media_type"` // Determines the file extension, e.g. video/mp4 or audio/mp4
+	ExpiresAt string `json:"expires_at"` // Time until which Path can be downloaded
 }
 
 type VideoDetails struct {
@@ -61,55 +136,137 @@ type VideoDetails struct {
 	DurationInMilliseconds int    `json:"duration_in_milliseconds"` // Duration of the video expressed in milliseconds. The value can be slightly different from the duration in the actual media files
 }
 
-func (c *Client) downloadSingleVideo(
+// prepareDownload resolves everything that may need user interaction (variant
+// selection, existing files) so the actual download can run unattended.
+// Skipped videos are returned as jobs with skip set.
+func (c *Client) prepareDownload(
 	ctx context.Context,
 	cfg *DownloadConfig,
+	index int,
 	variant *VideoVariant,
-) error {
+) (*downloadJob, error) {
 	videoID := cfg.VideoIDs[0]
 
 	videoDetails, err := c.fetchVideoDetails(ctx, videoID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch video details: %w", err)
+		return nil, fmt.Errorf("failed to fetch video details: %w", err)
+	}
+
+	tmpl := cfg.OutputTemplate
+	if tmpl == "" {
+		tmpl = defaultVideoTemplate
 	}
 
+	// The variant is resolved before the name, as its media type determines the extension
+	interactive := cfg.SelectVariant && isInteractive()
 	if variant == nil {
-		variant, err = c.resolveVideoVariant(ctx, videoID, cfg)
+		variant, err = c.resolveVideoVariant(ctx, videoID, interactive, cfg.Selector)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	opts := cfg.Videos[videoID]
 	outputFilename := cfg.Filename
+	if opts.Filename != "" {
+		outputFilename = opts.Filename
+	}
 	if outputFilename == "" {
-		if videoDetails.Title != "" {
-			outputFilename = ensureMp4Suffix(sanitizeFilename(videoDetails.Title))
-		} else {
-			outputFilename = "video.mp4"
+		values := newTemplateValues(videoID, videoDetails, cfg.ChannelName, variant, index+1)
+		if opts.Index > 0 {
+			values.Index = opts.Index
+		}
+		outputFilename, err = renderOutputTemplate(tmpl, values)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		outputFilename = ensureMp4Suffix(outputFilename)
 	}
-	fmt.Printf("Downloading video \"%s\"\n", outputFilename)
+	ext := extensionForMediaType(variant.MediaType)
+	outputFilename = ensureExtension(outputFilename, ext)
 
-	outputFile := filepath.Join(cfg.OutputDir, outputFilename)
+	candidateFile := filepath.Join(cfg.OutputDir, opts.Subdir, outputFilename)
+	if cfg.ExtractAudio && cfg.DeleteVideo { // only the audio file is kept
+		return c.prepareAudioOnlyDownload(videoID, videoDetails, variant, candidateFile, ext, cfg)
+	}
 
-	outputFile, err = handleExistingOutputFile(outputFile, cfg)
+	outputFile, err := c.handleExistingOutputFile(candidateFile, cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if outputFile == "" { // If skip was chosen in interactive mode (existing file)
-		return nil
+		return &downloadJob{videoID: videoID, details: videoDetails, outputFile: candidateFile, skip: true}, nil
+	}
+	outputFile = ensureExtension(outputFile, ext)
+	return newDownloadJob(videoID, videoDetails, variant, outputFile, audioPath(outputFile), cfg), nil
+}
+
+// prepareAudioOnlyDownload checks the .m4a file that is kept after the video
+// is deleted, and the video path the download uses until then. An existing
+// video is never replaced by this temporary one without --overwrite.
+func (c *Client) prepareAudioOnlyDownload(
+	videoID string,
+	videoDetails *VideoDetails,
+	variant *VideoVariant,
+	videoFile, ext string,
+	cfg *DownloadConfig,
+) (*downloadJob, error) {
+	candidateFile := audioPath(videoFile)
+	audioFile, err := c.handleExistingOutputFile(candidateFile, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if audioFile == "" { // If skip was chosen in interactive mode (existing file)
+		return &downloadJob{videoID: videoID, details: videoDetails, outputFile: candidateFile, skip: true}, nil
 	}
+	audioFile = ensureExtension(audioFile, audioExtension)
+	videoFile = strings.TrimSuffix(audioFile, filepath.Ext(audioFile)) + ext
 
-	downloadURL := c.BaseURL + variant.Path
-	return c.downloadFileFromURL(ctx, downloadURL, outputFile)
+	if _, statErr := os.Stat(videoFile); statErr == nil && !cfg.Overwrite {
+		if cfg.Skip {
+			fmt.Fprintf(c.Out, "File %s already exists. Skipping download.\n", videoFile)
+			return &downloadJob{videoID: videoID, details: videoDetails, outputFile: videoFile, skip: true}, nil
+		}
+		return nil, fmt.Errorf(
+			"video file %s already exists and would be deleted after extracting its audio. Use -w / --overwrite to replace it or -s / --skip to skip",
+			videoFile,
+		)
+	} else if statErr != nil && !os.IsNotExist(statErr) {
+		return nil, fmt.Errorf("error checking output file %s: %w", videoFile, statErr)
+	}
+	return newDownloadJob(videoID, videoDetails, variant, videoFile, audioFile, cfg), nil
+}
+
+func newDownloadJob(
+	videoID string,
+	videoDetails *VideoDetails,
+	variant *VideoVariant,
+	outputFile, audioFile string,
+	cfg *DownloadConfig,
+) *downloadJob {
+	return &downloadJob{
+		videoID:    videoID,
+		details:    videoDetails,
+		variant:    variant,
+		outputFile: outputFile,
+		audioFile:  audioFile,
+		segments:   cfg.Segments,
+		verify:     !cfg.NoVerify,
+		archive:    cfg.Archive,
+		channel:    cfg.ChannelName,
+
+		writeInfoJSON: cfg.WriteInfoJSON,
+		writeNFO:      cfg.WriteNFO,
+		embedMetadata: cfg.EmbedMetadata,
+		extractAudio:  cfg.ExtractAudio,
+		deleteVideo:   cfg.DeleteVideo,
+	}
 }
 
 func (c *Client) resolveVideoVariant(
 	ctx context.Context,
 	videoID string,
-	cfg *DownloadConfig,
+	interactive bool,
+	format *FormatSelector,
 ) (*VideoVariant, error) {
 	variants, err := c.fetchVideoVariants(ctx, videoID)
 	if err != nil {
@@ -117,13 +274,17 @@ func (c *Client) resolveVideoVariant(
 	}
 
 	if len(variants) == 0 {
-		return nil, fmt.Errorf("no video/mp4 variant found for video ID: %s", videoID)
+		return nil, fmt.Errorf("no variant found for video ID: %s", videoID)
 	}
 
-	if cfg.SelectVariant && isInteractive() && len(variants) > 1 {
-		return selectVariantInteractively(variants)
+	if interactive && len(variants) > 1 {
+		return c.selectVariantInteractively(variants)
+	}
+	variant, err := format.Select(variants)
+	if err != nil {
+		return nil, fmt.Errorf("%w for video ID: %s", err, videoID)
 	}
-	return selectBestVariant(variants), nil
+	return variant, nil
 }
 
 func (c *Client) DownloadVideos(ctx context.Context, cfg *DownloadConfig) *DownloadSummary {
@@ -132,12 +293,22 @@ func (c *Client) DownloadVideos(ctx context.Context, cfg *DownloadConfig) *Downl
 		Results: make([]DownloadResult, 0, len(cfg.VideoIDs)),
 	}
 
-	fmt.Printf("Starting download of %d video(s)\n", summary.Total)
+	fmt.Fprintf(c.Out, "Starting download of %d video(s)\n", summary.Total)
 
 	videoVariants := c.prepareVariants(ctx, cfg, summary)
 
+	// Prepare all downloads sequentially, as this may prompt the user
+	results := make([]DownloadResult, len(cfg.VideoIDs))
+	jobs := make([]*downloadJob, 0, len(cfg.VideoIDs))
 	for i, videoID := range cfg.VideoIDs {
-		result := c.processVideoDownload(
+		if cfg.Archive.Contains(videoID) {
+			fmt.Fprintf(c.Out, "\nVideo %s is already in the download archive. Skipping.\n", videoID)
+			results[i] = DownloadResult{VideoID: videoID, Skipped: true}
+			c.Events.Emit(Event{Type: EventSkipped, VideoID: videoID, Reason: "download archive"})
+			continue
+		}
+
+		job, err := c.processVideoDownload(
 			ctx,
 			videoID,
 			i,
@@ -145,44 +316,101 @@ func (c *Client) DownloadVideos(ctx context.Context, cfg *DownloadConfig) *Downl
 			cfg,
 			videoVariants[videoID],
 		)
+		if job != nil && slices.ContainsFunc(jobs, func(j *downloadJob) bool { return j.outputFile == job.outputFile }) {
+			err = fmt.Errorf("output file %s is already used by another video in this download", job.outputFile)
+			fmt.Fprintf(c.Out, "Failed to download video %s: %v\n", videoID, err)
+			job = nil
+		}
+		results[i] = DownloadResult{VideoID: videoID, Error: err}
+		switch {
+		case err != nil:
+			c.Events.Emit(Event{Type: EventFailed, VideoID: videoID, Error: err.Error()})
+		case job.skip:
+			results[i].OutputFile = job.outputFile
+			results[i].Skipped = true
+			results[i].Size = fileSize(job.outputFile)
+			c.Events.Emit(Event{
+				Type:       EventSkipped,
+				VideoID:    videoID,
+				Title:      job.details.Title,
+				OutputFile: job.outputFile,
+				Reason:     "file exists",
+			})
+		default:
+			results[i].OutputFile = job.outputFile
+			job.index = i
+			jobs = append(jobs, job)
+		}
+	}
+
+	for i, err := range c.runDownloadJobs(ctx, jobs, cfg.Jobs) {
+		results[jobs[i].index].Error = err
+		results[jobs[i].index].OutputFile = jobs[i].outputFile // changes if only the extracted audio is kept
+		results[jobs[i].index].Attempts = jobs[i].attempts
+		results[jobs[i].index].Size = jobs[i].size
+	}
+
+	for _, result := range results {
 		if result.Error != nil {
 			summary.Failed++
 		} else {
 			summary.Succeeded++
 		}
+		if result.Skipped {
+			summary.Skipped++
+		}
 		summary.Results = append(summary.Results, result)
 	}
 
 	if summary.Total > 1 {
-		printDownloadSummary(summary)
+		c.printDownloadSummary(summary)
 	}
 
 	return summary
 }
 
-func (c *Client) DownloadChannel(ctx context.Context, cfg *DownloadConfig) error {
+// DownloadChannel downloads the selected videos of cfg.ChannelID. Videos that
+// are in the download archive or not selected are not part of the summary.
+func (c *Client) DownloadChannel(ctx context.Context, cfg *DownloadConfig) (*DownloadSummary, error) {
 	channelDetails, err := c.fetchChannelDetails(ctx, cfg.ChannelID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch channel details: %w", err)
+		return nil, fmt.Errorf("failed to fetch channel details: %w", err)
 	}
 
 	channelVideos, err := c.fetchChannelVideos(ctx, cfg.ChannelID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch channel videos: %w", err)
+		return nil, fmt.Errorf("failed to fetch channel videos: %w", err)
 	}
 
 	if len(channelVideos) == 0 {
-		fmt.Println("No videos found in this channel.")
-		return nil
+		fmt.Fprintln(c.Out, "No videos found in this channel.")
+		return &DownloadSummary{Results: []DownloadResult{}}, nil
 	}
 
-	fmt.Printf("Found %d videos in channel '%s'\n", len(channelVideos), channelDetails.Name)
+	fmt.Fprintf(c.Out, "Found %d videos in channel '%s'\n", len(channelVideos), channelDetails.Name)
+
+	videoOptions := make(map[string]VideoOptions, len(channelVideos))
+	for i, v := range channelVideos {
+		videoOptions[v.ID] = VideoOptions{Index: i + 1}
+	}
+
+	found := len(channelVideos)
+	channelVideos = slices.DeleteFunc(channelVideos, func(v ChannelVideo) bool {
+		return cfg.Archive.Contains(v.ID)
+	})
+	if archived := found - len(channelVideos); archived > 0 {
+		fmt.Fprintf(c.Out, "Skipping %d video(s) already in the download archive\n", archived)
+	}
+	if len(channelVideos) == 0 {
+		fmt.Fprintln(c.Out, "No new videos in this channel.")
+		return &DownloadSummary{Results: []DownloadResult{}}, nil
+	}
 
 	videos := make([]*VideoDetails, len(channelVideos))
 	for i, v := range channelVideos {
 		details, fetchErr := c.fetchVideoDetails(ctx, v.ID)
 		if fetchErr != nil {
-			return fmt.Errorf("failed to fetch video details for %s: %w", v.ID, fetchErr)
+			return nil, fmt.Errorf("failed to fetch video details for %s: %w", v.ID, fetchErr)
 		}
 		videos[i] = details
 	}
@@ -191,41 +419,35 @@ func (c *Client) DownloadChannel(ctx context.Context, cfg *DownloadConfig) error
 	if cfg.All {
 		selectedVideos = videos
 	} else {
-		selectedVideos, err = selectVideosInteractively(videos)
+		selectedVideos, err = c.selectVideosInteractively(videos)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if len(selectedVideos) == 0 {
-		fmt.Println("No videos selected.")
-		return nil
+		fmt.Fprintln(c.Out, "No videos selected.")
+		return &DownloadSummary{Results: []DownloadResult{}}, nil
 	}
 
-	// create subdirectory for channel videos
-	channelDir := filepath.Join(cfg.OutputDir, sanitizeFilename(channelDetails.Name))
-	if mkdirErr := os.MkdirAll(channelDir, DefaultDirectoryPermissions); mkdirErr != nil {
-		return fmt.Errorf("failed to create channel directory: %w", mkdirErr)
-	}
-	fmt.Printf("Downloading %d video(s) to '%s'\n", len(selectedVideos), channelDir)
+	fmt.Fprintf(c.Out, "Downloading %d video(s) to '%s'\n", len(selectedVideos), cfg.OutputDir)
 
 	videoIDs := make([]string, len(selectedVideos))
 	for i, v := range selectedVideos {
 		videoIDs[i] = v.ID
 	}
 
-	videoCfg := &DownloadConfig{
-		AccessToken:   cfg.AccessToken,
-		OutputDir:     channelDir,
-		Overwrite:     cfg.Overwrite,
-		Skip:          cfg.Skip,
-		SelectVariant: cfg.SelectVariant,
-		VideoIDs:      videoIDs,
+	// videos are stored in a subdirectory per channel unless the template says otherwise
+	videoCfg := *cfg
+	videoCfg.VideoIDs = videoIDs
+	videoCfg.Filename = ""
+	videoCfg.ChannelName = channelDetails.Name
+	videoCfg.Videos = videoOptions
+	if videoCfg.OutputTemplate == "" {
+		videoCfg.OutputTemplate = defaultChannelTemplate
 	}
 
-	c.DownloadVideos(ctx, videoCfg)
-
-	return nil
+	return c.DownloadVideos(ctx, &videoCfg), nil
 }
 
 func (c *Client) prepareVariants(
@@ -241,7 +463,7 @@ func (c *Client) prepareVariants(
 
 	individualSelection, selectionErr := c.promptForQualitySelection(ctx, cfg)
 	if selectionErr != nil {
-		fmt.Printf("Warning: failed to select quality: %v. Using best quality.\n", selectionErr)
+		fmt.Fprintf(c.Out, "Warning: failed to select quality: %v. Using format %q.\n", selectionErr, cfg.Selector)
 		cfg.SelectVariant = false
 		return videoVariants
 	}
@@ -251,17 +473,20 @@ func (c *Client) prepareVariants(
 	}
 
 	for i, videoID := range cfg.VideoIDs {
-		fmt.Printf("\nProcessing video %d/%d (ID: %s)\n", i+1, summary.Total, videoID)
+		if cfg.Archive.Contains(videoID) {
+			continue
+		}
+		fmt.Fprintf(c.Out, "\nProcessing video %d/%d (ID: %s)\n", i+1, summary.Total, videoID)
 
 		variants, variantErr := c.fetchVideoVariants(ctx, videoID)
 		if variantErr != nil {
-			fmt.Printf("Failed to fetch variants for video %s: %v\n", videoID, variantErr)
+			fmt.Fprintf(c.Out, "Failed to fetch variants for video %s: %v\n", videoID, variantErr)
 			continue
 		}
 
-		variant, selectErr := selectVariantInteractively(variants)
+		variant, selectErr := c.selectVariantInteractively(variants)
 		if selectErr != nil {
-			fmt.Printf("Failed to select variant for video %s: %v\n", videoID, selectErr)
+			fmt.Fprintf(c.Out, "Failed to select variant for video %s: %v\n", videoID, selectErr)
 			continue
 		}
 
@@ -278,23 +503,15 @@ func (c *Client) processVideoDownload(
 	total int,
 	cfg *DownloadConfig,
 	variant *VideoVariant,
-) DownloadResult {
-	fmt.Printf("\nProcessing video %d/%d (ID: %s)\n", index+1, total, videoID)
+) (*downloadJob, error) {
+	fmt.Fprintf(c.Out, "\nProcessing video %d/%d (ID: %s)\n", index+1, total, videoID)
 
-	videoCfg := &DownloadConfig{
-		AccessToken:   cfg.AccessToken,
-		OutputDir:     cfg.OutputDir,
-		Overwrite:     cfg.Overwrite,
-		Skip:          cfg.Skip,
-		SelectVariant: cfg.SelectVariant,
-		VideoIDs:      []string{videoID},
-		Filename:      cfg.Filename,
-	}
+	videoCfg := *cfg
+	videoCfg.VideoIDs = []string{videoID}
 
-	err := c.downloadSingleVideo(ctx, videoCfg, variant)
+	job, err := c.prepareDownload(ctx, &videoCfg, index, variant)
 	if err != nil {
-		fmt.Printf("Failed to download video %s: %v\n", videoID, err)
+		fmt.Fprintf(c.Out, "Failed to download video %s: %v\n", videoID, err)
 	}
-
-	return DownloadResult{VideoID: videoID, Error: err}
+	return job, err
 }
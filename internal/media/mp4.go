@@ -0,0 +1,146 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+const (
+	boxHeaderSize      = 8
+	largeBoxHeaderSize = 16
+)
+
+var errInvalidMP4 = errors.New("invalid MP4 file")
+
+// mp4ContainerBoxes are the boxes whose payload consists of child boxes.
+var mp4ContainerBoxes = []string{"moov", "trak", "mdia", "minf", "stbl", "edts", "dinf", "mvex", "moof", "traf"}
+
+// mp4Box is the location of a box (ISO/IEC 14496-12 "atom") in a file.
+type mp4Box struct {
+	Type       string
+	Offset     int64 // Start of the box header
+	HeaderSize int64
+	Size       int64 // Including the header
+}
+
+func (b mp4Box) payloadOffset() int64 {
+	return b.Offset + b.HeaderSize
+}
+
+func (b mp4Box) payloadSize() int64 {
+	return b.Size - b.HeaderSize
+}
+
+func (b mp4Box) end() int64 {
+	return b.Offset + b.Size
+}
+
+// readBoxHeader reads the header of the box at offset, which must end before limit.
+func readBoxHeader(r io.ReaderAt, offset, limit int64) (mp4Box, error) {
+	if limit-offset < boxHeaderSize {
+		return mp4Box{}, fmt.Errorf("%w: truncated box header at offset %d", errInvalidMP4, offset)
+	}
+	var header [largeBoxHeaderSize]byte
+	if _, err := r.ReadAt(header[:boxHeaderSize], offset); err != nil {
+		return mp4Box{}, fmt.Errorf("failed to read box header at offset %d: %w", offset, err)
+	}
+
+	box := mp4Box{
+		Type:       string(header[4:8]),
+		Offset:     offset,
+		HeaderSize: boxHeaderSize,
+		Size:       int64(binary.BigEndian.Uint32(header[:4])),
+	}
+	switch box.Size {
+	case 0: // the box extends to the end of the file
+		box.Size = limit - offset
+	case 1: // 64-bit size follows the type
+		if limit-offset < largeBoxHeaderSize {
+			return mp4Box{}, fmt.Errorf("%w: truncated %q box header", errInvalidMP4, box.Type)
+		}
+		if _, err := r.ReadAt(header[boxHeaderSize:], offset+boxHeaderSize); err != nil {
+			return mp4Box{}, fmt.Errorf("failed to read box header at offset %d: %w", offset, err)
+		}
+		box.HeaderSize = largeBoxHeaderSize
+		box.Size = int64(binary.BigEndian.Uint64(header[boxHeaderSize:])) //nolint:gosec // checked below
+	}
+
+	if box.Size < box.HeaderSize || box.Size > limit-offset {
+		return mp4Box{}, fmt.Errorf("%w: %q box at offset %d has size %d, but only %d bytes remain",
+			errInvalidMP4, box.Type, offset, box.Size, limit-offset)
+	}
+	return box, nil
+}
+
+// readBoxes reads the headers of consecutive boxes filling start to end exactly.
+func readBoxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for offset := start; offset < end; {
+		box, err := readBoxHeader(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, box)
+		offset = box.end()
+	}
+	return boxes, nil
+}
+
+// checkBoxTree checks that the children of container boxes fill their parent exactly.
+func checkBoxTree(r io.ReaderAt, boxes []mp4Box) error {
+	for _, box := range boxes {
+		if !slices.Contains(mp4ContainerBoxes, box.Type) {
+			continue
+		}
+		children, err := readBoxes(r, box.payloadOffset(), box.end())
+		if err != nil {
+			return fmt.Errorf("inside %s box: %w", box.Type, err)
+		}
+		if err = checkBoxTree(r, children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findBox(boxes []mp4Box, boxType string) (mp4Box, bool) {
+	i := slices.IndexFunc(boxes, func(b mp4Box) bool { return b.Type == boxType })
+	if i < 0 {
+		return mp4Box{}, false
+	}
+	return boxes[i], true
+}
+
+// readBoxPayload reads the payload of a box that is expected to be small.
+func readBoxPayload(r io.ReaderAt, box mp4Box) ([]byte, error) {
+	data := make([]byte, box.payloadSize())
+	if _, err := r.ReadAt(data, box.payloadOffset()); err != nil {
+		return nil, fmt.Errorf("failed to read %s box: %w", box.Type, err)
+	}
+	return data, nil
+}
+
+// parseMovieHeader returns the timescale (units per second) and duration of an mvhd payload.
+func parseMovieHeader(data []byte) (uint32, uint64, error) {
+	const (
+		v0Size = 4 + 4 + 4 + 4 + 4 // version/flags, creation, modification, timescale, duration
+		v1Size = 4 + 8 + 8 + 4 + 8
+	)
+	if len(data) < v0Size {
+		return 0, 0, fmt.Errorf("%w: truncated mvhd box", errInvalidMP4)
+	}
+	switch data[0] {
+	case 0:
+		return binary.BigEndian.Uint32(data[12:16]), uint64(binary.BigEndian.Uint32(data[16:20])), nil
+	case 1:
+		if len(data) < v1Size {
+			return 0, 0, fmt.Errorf("%w: truncated mvhd box", errInvalidMP4)
+		}
+		return binary.BigEndian.Uint32(data[20:24]), binary.BigEndian.Uint64(data[24:32]), nil
+	default:
+		return 0, 0, fmt.Errorf("%w: unknown mvhd version %d", errInvalidMP4, data[0])
+	}
+}
@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// box builds a box from the concatenated payload parts.
+func box(boxType string, payload ...[]byte) []byte {
+	return makeBox(boxType, bytes.Join(payload, nil))
+}
+
+// u32s encodes the values as big-endian 32-bit integers.
+func u32s(values ...uint32) []byte {
+	var b []byte
+	for _, value := range values {
+		b = binary.BigEndian.AppendUint32(b, value)
+	}
+	return b
+}
+
+func ftypBox() []byte {
+	return box("ftyp", []byte("isom"), u32s(0x200), []byte("isomiso2mp41"))
+}
+
+// mvhdBox builds a version 0 movie header.
+func mvhdBox(timescale, duration uint32) []byte {
+	payload := make([]byte, 100)
+	binary.BigEndian.PutUint32(payload[12:], timescale)
+	binary.BigEndian.PutUint32(payload[16:], duration)
+	return box("mvhd", payload)
+}
+
+// trakBox builds a track with the media handler ("vide", "soun") and the sample table boxes.
+func trakBox(handler string, tables ...[]byte) []byte {
+	hdlr := box("hdlr", u32s(0, 0), []byte(handler), make([]byte, 13))
+	stbl := box("stbl", tables...)
+	return box("trak",
+		box("tkhd", make([]byte, 84)),
+		box("mdia", box("mdhd", make([]byte, 24)), hdlr, box("minf", stbl)),
+	)
+}
+
+// chunkOffsetBox builds an stco box, or a co64 box if co64 is set.
+func chunkOffsetBox(co64 bool, offsets ...int64) []byte {
+	payload := u32s(0, uint32(len(offsets)))
+	for _, offset := range offsets {
+		if co64 {
+			payload = binary.BigEndian.AppendUint64(payload, uint64(offset))
+		} else {
+			payload = binary.BigEndian.AppendUint32(payload, uint32(offset))
+		}
+	}
+	if co64 {
+		return box("co64", payload)
+	}
+	return box("stco", payload)
+}
+
+// buildMP4 returns ftyp, moov and an mdat box holding the chunks, with moov
+// before or after mdat. moov builds the moov box from the chunk offsets, its
+// size must not depend on them.
+func buildMP4(moovFirst bool, moov func(offsets []int64) []byte, chunks ...[]byte) []byte {
+	ftyp := ftypBox()
+	mdatStart := int64(len(ftyp))
+	if moovFirst {
+		mdatStart += int64(len(moov(make([]int64, len(chunks)))))
+	}
+	offsets := make([]int64, len(chunks))
+	position := mdatStart + boxHeaderSize
+	for i, chunk := range chunks {
+		offsets[i] = position
+		position += int64(len(chunk))
+	}
+
+	mdat := box("mdat", chunks...)
+	if moovFirst {
+		return bytes.Join([][]byte{ftyp, moov(offsets), mdat}, nil)
+	}
+	return bytes.Join([][]byte{ftyp, mdat, moov(offsets)}, nil)
+}
+
+// writeTestFile writes data to a file in a temporary directory and returns its path.
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readChunkOffsets returns the entries of all stco and co64 boxes of the file.
+func readChunkOffsets(t *testing.T, data []byte) []int64 {
+	t.Helper()
+	boxes, err := readBoxes(bytes.NewReader(data), 0, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moov, ok := findBox(boxes, "moov")
+	if !ok {
+		t.Fatal("no moov box")
+	}
+	var offsets []int64
+	err = walkBoxes(data, moov.payloadOffset(), moov.end(), func(box mp4Box) error {
+		payload := data[box.payloadOffset():box.end()]
+		var stco, co64 []byte
+		if box.Type == "stco" {
+			stco = payload
+		} else {
+			co64 = payload
+		}
+		boxOffsets, err := parseChunkOffsets(stco, co64)
+		offsets = append(offsets, boxOffsets...)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return offsets
+}
+
+func TestReadBoxHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantSize int64
+		wantErr  bool
+	}{
+		{"regular", box("free", make([]byte, 4)), "free", 12, false},
+		{"64-bit size", bytes.Join([][]byte{u32s(1), []byte("mdat"), binary.BigEndian.AppendUint64(nil, 20), u32s(0)}, nil), "mdat", 20, false},
+		{"extends to the end", append(u32s(0), []byte("mdat\x00\x00")...), "mdat", 10, false},
+		{"truncated header", []byte{0, 0, 0}, "", 0, true},
+		{"truncated 64-bit header", append(u32s(1), []byte("mdat")...), "", 0, true},
+		{"larger than the data", append(u32s(100), []byte("moov")...), "", 0, true},
+		{"smaller than its header", append(u32s(4), []byte("moov")...), "", 0, true},
+		{"negative 64-bit size", append(u32s(1), append([]byte("mdat"), binary.BigEndian.AppendUint64(nil, 1<<63)...)...), "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Padding after the data must not be read, the limit ends the box
+			padded := append(bytes.Clone(tt.data), make([]byte, 32)...)
+			got, err := readBoxHeader(bytes.NewReader(padded), 0, int64(len(tt.data)))
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidMP4) {
+					t.Errorf("got %+v, %v, want an invalid MP4 error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != tt.wantType || got.Size != tt.wantSize {
+				t.Errorf("got %q with size %d, want %q with size %d", got.Type, got.Size, tt.wantType, tt.wantSize)
+			}
+		})
+	}
+}
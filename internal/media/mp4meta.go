@@ -0,0 +1,281 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// iTunes metadata item types. The first byte of the text items is © (0xA9).
+const (
+	tagTitle   = "\xa9nam"
+	tagArtist  = "\xa9ART"
+	tagAlbum   = "\xa9alb"
+	tagDate    = "\xa9day"
+	tagComment = "\xa9cmt"
+)
+
+// dataTypeUTF8 is the well-known type of text values in ilst data boxes.
+const dataTypeUTF8 = 1
+
+var errFragmentedMP4 = errors.New("fragmented MP4 files are not supported")
+
+// mp4Tag is a text item of the iTunes-style ilst box.
+type mp4Tag struct {
+	Type  string
+	Value string
+}
+
+// metadataTags returns the tags embedded into downloads of the job.
+func (c *Client) metadataTags(job *downloadJob) []mp4Tag {
+	tags := []mp4Tag{{tagTitle, job.details.Title}}
+	if job.channel != "" {
+		tags = append(tags, mp4Tag{tagArtist, job.channel}, mp4Tag{tagAlbum, job.channel})
+	}
+	if published, err := time.Parse(time.RFC3339, job.details.PublishedAt); err == nil {
+		tags = append(tags, mp4Tag{tagDate, published.Format(time.DateOnly)})
+	}
+	return append(tags, mp4Tag{tagComment, c.videoPageURL(job.videoID)})
+}
+
+// embedMetadata replaces the moov/udta/meta box of the file with the given tags.
+// The file is rewritten next to the original and renamed over it. If moov is
+// placed before the media data, the chunk offsets are shifted by its growth.
+func embedMetadata(path string, tags []mp4Tag) error {
+	tmpName, err := writeWithMetadata(path, tags)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeWithMetadata writes a copy of path with the new moov box into a
+// temporary file and returns its name.
+func writeWithMetadata(path string, tags []mp4Tag) (_ string, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", path, cerr)
+		}
+	}()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	boxes, err := readBoxes(src, 0, info.Size())
+	if err != nil {
+		return "", err
+	}
+	moov, ok := findBox(boxes, "moov")
+	if !ok {
+		return "", fmt.Errorf("%w: no moov box", errInvalidMP4)
+	}
+
+	oldMoov := make([]byte, moov.Size)
+	if _, err = src.ReadAt(oldMoov, moov.Offset); err != nil {
+		return "", fmt.Errorf("failed to read moov box: %w", err)
+	}
+	newMoov, err := rebuildMoov(oldMoov, moov.HeaderSize, tags)
+	if err != nil {
+		return "", err
+	}
+	delta := int64(len(newMoov)) - moov.Size
+	if err = shiftChunkOffsets(newMoov, moov.end(), delta); err != nil {
+		return "", err
+	}
+
+	return writeTempFile(path, func(out io.Writer) error {
+		if _, err := io.Copy(out, io.NewSectionReader(src, 0, moov.Offset)); err != nil {
+			return err
+		}
+		if _, err := out.Write(newMoov); err != nil {
+			return err
+		}
+		_, err := io.Copy(out, io.NewSectionReader(src, moov.end(), info.Size()-moov.end()))
+		return err
+	})
+}
+
+// rebuildMoov returns a copy of the moov box with a new udta/meta box. Other
+// udta children are kept, unless they can't be parsed.
+func rebuildMoov(moov []byte, headerSize int64, tags []mp4Tag) ([]byte, error) {
+	r := bytes.NewReader(moov)
+	children, err := readBoxes(r, headerSize, int64(len(moov)))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload, udtaPayload []byte
+	for _, child := range children {
+		switch child.Type {
+		case "mvex":
+			return nil, errFragmentedMP4
+		case "udta":
+			udtaPayload = keepUserData(moov, child)
+		default:
+			payload = append(payload, moov[child.Offset:child.end()]...)
+		}
+	}
+
+	udtaPayload = append(udtaPayload, metaBox(tags)...)
+	payload = append(payload, makeBox("udta", udtaPayload)...)
+	return makeBox("moov", payload), nil
+}
+
+// keepUserData returns the udta children except an existing meta box.
+// Some writers end udta with a 32-bit zero terminator, which is dropped.
+func keepUserData(moov []byte, udta mp4Box) []byte {
+	var kept []byte
+	r := bytes.NewReader(moov)
+	for offset := udta.payloadOffset(); offset < udta.end(); {
+		box, err := readBoxHeader(r, offset, udta.end())
+		if err != nil {
+			break
+		}
+		if box.Type != "meta" {
+			kept = append(kept, moov[box.Offset:box.end()]...)
+		}
+		offset = box.end()
+	}
+	return kept
+}
+
+// metaBox builds the meta box with an mdir handler and the ilst items.
+func metaBox(tags []mp4Tag) []byte {
+	hdlr := make([]byte, 0, 25)     //nolint:mnd // size of the fields below
+	hdlr = append(hdlr, 0, 0, 0, 0) // version and flags
+	hdlr = append(hdlr, 0, 0, 0, 0) // pre_defined
+	hdlr = append(hdlr, "mdir"...)  // handler type
+	hdlr = append(hdlr, "appl"...)  // reserved, set by iTunes
+	hdlr = append(hdlr, make([]byte, 8)...)
+	hdlr = append(hdlr, 0) // empty name
+
+	var ilst []byte
+	for _, tag := range tags {
+		data := binary.BigEndian.AppendUint32(nil, dataTypeUTF8)
+		data = append(data, 0, 0, 0, 0) // locale
+		data = append(data, tag.Value...)
+		ilst = append(ilst, makeBox(tag.Type, makeBox("data", data))...)
+	}
+
+	meta := []byte{0, 0, 0, 0} // version and flags
+	meta = append(meta, makeBox("hdlr", hdlr)...)
+	meta = append(meta, makeBox("ilst", ilst)...)
+	return makeBox("meta", meta)
+}
+
+func makeBox(boxType string, payload []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(boxHeaderSize+len(payload))) //nolint:gosec // metadata boxes are small
+	box = append(box, boxType...)
+	return append(box, payload...)
+}
+
+// shiftChunkOffsets adds delta to all stco/co64 entries pointing at or after
+// threshold, i.e. into data that moves because the moov box changed size.
+func shiftChunkOffsets(moov []byte, threshold, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	return walkBoxes(moov, 0, int64(len(moov)), func(box mp4Box) error {
+		const entriesOffset = 8 // version/flags and entry count
+		payload := moov[box.payloadOffset():box.end()]
+		if len(payload) < entriesOffset {
+			return fmt.Errorf("%w: truncated %s box", errInvalidMP4, box.Type)
+		}
+		count := int(binary.BigEndian.Uint32(payload[4:8]))
+		entries := payload[entriesOffset:]
+
+		switch box.Type {
+		case "stco":
+			if len(entries) < count*4 {
+				return fmt.Errorf("%w: truncated stco box", errInvalidMP4)
+			}
+			for i := range count {
+				offset := int64(binary.BigEndian.Uint32(entries[i*4:]))
+				if offset < threshold {
+					continue
+				}
+				offset += delta
+				if offset > math.MaxUint32 {
+					return errors.New("chunk offset exceeds 32 bits after adding metadata")
+				}
+				binary.BigEndian.PutUint32(entries[i*4:], uint32(offset))
+			}
+		case "co64":
+			if len(entries) < count*8 {
+				return fmt.Errorf("%w: truncated co64 box", errInvalidMP4)
+			}
+			for i := range count {
+				offset := int64(binary.BigEndian.Uint64(entries[i*8:])) //nolint:gosec // file offsets fit
+				if offset >= threshold {
+					binary.BigEndian.PutUint64(entries[i*8:], uint64(offset+delta)) //nolint:gosec // still positive
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// walkBoxes calls fn for every stco and co64 box in data[start:end], descending into container boxes.
+func walkBoxes(data []byte, start, end int64, fn func(mp4Box) error) error {
+	boxes, err := readBoxes(bytes.NewReader(data), start, end)
+	if err != nil {
+		return err
+	}
+	for _, box := range boxes {
+		switch {
+		case box.Type == "stco" || box.Type == "co64":
+			err = fn(box)
+		case slices.Contains(mp4ContainerBoxes, box.Type):
+			err = walkBoxes(data, box.payloadOffset(), box.end(), fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTempFile writes a file next to path with write, flushes it to disk
+// and returns its name. The file is removed if anything fails.
+func writeTempFile(path string, write func(out io.Writer) error) (_ string, err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err = tmp.Chmod(DefaultFilePermissions); err != nil {
+		return "", fmt.Errorf("failed to set permissions of %s: %w", tmp.Name(), err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return "", fmt.Errorf("failed to flush %s: %w", tmp.Name(), err)
+	}
+	if err = tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	return tmp.Name(), nil
+}
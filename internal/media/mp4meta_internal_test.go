@@ -0,0 +1,126 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+var testChunks = [][]byte{[]byte("first chunk"), []byte("second chunk"), []byte("third chunk")}
+
+// videoMoov returns a moov builder with one video track, and optionally an
+// existing udta box.
+func videoMoov(co64 bool, udta []byte) func(offsets []int64) []byte {
+	return func(offsets []int64) []byte {
+		trak := trakBox("vide",
+			box("stsd", u32s(0, 0)),
+			box("stsz", u32s(0, 0, 3, 11, 12, 11)),
+			box("stsc", u32s(0, 1, 1, 1, 1)),
+			chunkOffsetBox(co64, offsets...),
+		)
+		return box("moov", mvhdBox(1000, 60000), trak, udta)
+	}
+}
+
+func TestEmbedMetadata(t *testing.T) {
+	oldUserData := box("udta",
+		box("meta", u32s(0), box("ilst", box(tagTitle, box("data", u32s(dataTypeUTF8, 0), []byte("Old title"))))),
+		box("cprt", []byte("kept")),
+		u32s(0), // terminator written by some tools
+	)
+	tests := []struct {
+		name      string
+		moovFirst bool
+		co64      bool
+		udta      []byte
+	}{
+		{"stco, moov before mdat", true, false, nil},
+		{"stco, moov after mdat", false, false, nil},
+		{"co64, moov before mdat", true, true, nil},
+		{"co64, moov after mdat", false, true, nil},
+		{"existing metadata, moov before mdat", true, false, oldUserData},
+		{"existing metadata, moov after mdat", false, true, oldUserData},
+	}
+	tags := []mp4Tag{{tagTitle, "New title"}, {tagArtist, "Channel"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := buildMP4(tt.moovFirst, videoMoov(tt.co64, tt.udta), testChunks...)
+			path := writeTestFile(t, "video.mp4", original)
+
+			if err := embedMetadata(path, tags); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = VerifyMP4(path, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			offsets := readChunkOffsets(t, data)
+			for i, offset := range offsets {
+				if chunk := testChunks[i]; !bytes.Equal(data[offset:offset+int64(len(chunk))], chunk) {
+					t.Errorf("chunk %d at offset %d is %q, want %q", i, offset, data[offset:offset+int64(len(chunk))], chunk)
+				}
+			}
+			originalOffsets := readChunkOffsets(t, original)
+			if moved := !slices.Equal(offsets, originalOffsets); moved != tt.moovFirst {
+				t.Errorf("offsets changed from %v to %v, want them moved only if moov is before mdat", originalOffsets, offsets)
+			}
+
+			if !bytes.Contains(data, []byte("New title")) || !bytes.Contains(data, []byte("Channel")) {
+				t.Error("tags are missing")
+			}
+			if bytes.Contains(data, []byte("Old title")) || bytes.Count(data, []byte("ilst")) != 1 {
+				t.Error("existing metadata wasn't replaced")
+			}
+			if tt.udta != nil && !bytes.Contains(data, box("cprt", []byte("kept"))) {
+				t.Error("other user data wasn't kept")
+			}
+		})
+	}
+}
+
+func TestEmbedMetadataRejectsMalformedFiles(t *testing.T) {
+	valid := buildMP4(true, videoMoov(false, nil), testChunks...)
+	truncatedStco := func(offsets []int64) []byte {
+		stco := box("stco", u32s(0, 5, uint32(offsets[0]))) // 5 entries, only 1 present
+		return box("moov", mvhdBox(1000, 60000), trakBox("vide", stco))
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"truncated file", valid[:len(valid)-5], errInvalidMP4},
+		{"truncated moov child", bytes.Replace(valid, mvhdBox(1000, 60000)[:8], append(u32s(500), "mvhd"...), 1), errInvalidMP4},
+		{"no moov", bytes.Join([][]byte{ftypBox(), box("mdat", testChunks...)}, nil), errInvalidMP4},
+		{"truncated stco", buildMP4(true, truncatedStco, testChunks...), errInvalidMP4},
+		{"truncated stco header", buildMP4(true, func([]int64) []byte {
+			return box("moov", mvhdBox(1000, 60000), trakBox("vide", box("stco", u32s(0))))
+		}, testChunks...), errInvalidMP4},
+		{"fragmented", buildMP4(true, func([]int64) []byte {
+			return box("moov", mvhdBox(1000, 60000), box("mvex", box("trex", make([]byte, 24))))
+		}, testChunks...), errFragmentedMP4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "video.mp4", tt.data)
+
+			err := embedMetadata(path, []mp4Tag{{tagTitle, "Title"}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			data, readErr := os.ReadFile(path)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			if !bytes.Equal(data, tt.data) {
+				t.Error("the file was changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// listPages iterates over every item of a paginated list endpoint. Pages are
+// followed until no next page is announced, see nextPageURL.
+func listPages[T any](ctx context.Context, c *Client, firstURL string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		base, err := url.Parse(c.BaseURL)
+		if err != nil {
+			yield(zero, fmt.Errorf("invalid base URL %q: %w", c.BaseURL, err))
+			return
+		}
+		seen := make(map[string]bool)
+		for pageURL := firstURL; pageURL != ""; {
+			if seen[pageURL] { // guard against servers linking back to an earlier page
+				return
+			}
+			seen[pageURL] = true
+
+			var page []T
+			header, err := c.getJSONWithHeader(ctx, pageURL, &page)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(page) == 0 {
+				return
+			}
+
+			pageURL, err = nextPageURL(base, pageURL, header)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
+
+// collectPages returns all items of a paginated list endpoint.
+func collectPages[T any](ctx context.Context, c *Client, firstURL string) ([]T, error) {
+	var items []T
+	for item, err := range listPages[T](ctx, c, firstURL) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// nextPageURL returns the URL of the page after currentURL, or "" if it is
+// the last one. The next page is announced by a Link header (rel="next"),
+// resolved against the current page, or by the X-Next-Page or X-Total-Pages
+// headers, which select it with the page parameter. As the access token is
+// sent with every page, links to another host than base are refused.
+func nextPageURL(base *url.URL, currentURL string, header http.Header) (string, error) {
+	current, err := url.Parse(currentURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid page URL %q: %w", currentURL, err)
+	}
+
+	if target := nextLinkTarget(header); target != "" {
+		next, err := current.Parse(target)
+		if err != nil {
+			return "", fmt.Errorf("invalid next page link %q: %w", target, err)
+		}
+		if !strings.EqualFold(next.Scheme, base.Scheme) || !strings.EqualFold(next.Host, base.Host) {
+			return "", fmt.Errorf("refusing next page link to %s, it is not on %s", next.Redacted(), base.Host)
+		}
+		return next.String(), nil
+	}
+
+	page := 1
+	if value := current.Query().Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("invalid page parameter %q: %w", value, err)
+		}
+	}
+	next, ok := nextPageNumber(page, header)
+	if !ok {
+		return "", nil
+	}
+	query := current.Query()
+	query.Set("page", strconv.Itoa(next))
+	current.RawQuery = query.Encode()
+	return current.String(), nil
+}
+
+// nextLinkTarget returns the rel="next" target of the Link headers, "" if there is none.
+func nextLinkTarget(header http.Header) string {
+	for _, link := range header.Values("Link") {
+		for part := range strings.SplitSeq(link, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+			if ok && isNextRel(params) {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// nextPageNumber reads the page number after page from the pagination
+// headers of Rails APIs. Without them the list is a single page.
+func nextPageNumber(page int, header http.Header) (int, bool) {
+	if next, err := strconv.Atoi(header.Get("X-Next-Page")); err == nil {
+		return next, next > page
+	}
+	if total, err := strconv.Atoi(header.Get("X-Total-Pages")); err == nil {
+		return page + 1, page < total
+	}
+	return 0, false
+}
+
+func isNextRel(params string) bool {
+	for param := range strings.SplitSeq(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(key, "rel") {
+			continue
+		}
+		for rel := range strings.FieldsSeq(strings.Trim(value, `"`)) {
+			if strings.EqualFold(rel, "next") {
+				return true
+			}
+		}
+	}
+	return false
+}
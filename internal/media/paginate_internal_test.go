@@ -0,0 +1,176 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newPagedServer serves the channel videos v1..v<total> in pages of perPage,
+// with the headers of each page set by pageHeader.
+func newPagedServer(t *testing.T, total, perPage int, pageHeader func(h http.Header, page, pages int)) *httptest.Server {
+	t.Helper()
+	pages := (total + perPage - 1) / perPage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/browse/channels/ch1/videos" {
+			http.NotFound(w, r)
+			return
+		}
+		page := 1
+		if value := r.URL.Query().Get("page"); value != "" {
+			page, _ = strconv.Atoi(value)
+		}
+		videos := []ChannelVideo{}
+		for i := (page-1)*perPage + 1; i <= min(page*perPage, total); i++ {
+			videos = append(videos, ChannelVideo{ID: fmt.Sprintf("v%d", i)})
+		}
+		pageHeader(w.Header(), page, pages)
+		if err := json.NewEncoder(w).Encode(videos); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func fetchIDs(t *testing.T, baseURL string) ([]string, error) {
+	t.Helper()
+	c := NewClient("token")
+	c.BaseURL = baseURL
+	videos, err := c.fetchChannelVideos(context.Background(), "ch1")
+	ids := make([]string, len(videos))
+	for i, video := range videos {
+		ids[i] = video.ID
+	}
+	return ids, err
+}
+
+func wantIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("v%d", i+1)
+	}
+	return ids
+}
+
+func TestListPagesFollowsLinkHeader(t *testing.T) {
+	var srv *httptest.Server
+	srv = newPagedServer(t, 7, 3, func(h http.Header, page, pages int) {
+		if page < pages {
+			h.Set("Link", fmt.Sprintf(`<%s/api/v1/browse/channels/ch1/videos?page=%d>; rel="next", <%s>; rel="first"`,
+				srv.URL, page+1, srv.URL))
+		}
+	})
+
+	ids, err := fetchIDs(t, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, wantIDs(7)) {
+		t.Errorf("got %v, want %v", ids, wantIDs(7))
+	}
+}
+
+func TestListPagesResolvesRelativeLink(t *testing.T) {
+	srv := newPagedServer(t, 5, 2, func(h http.Header, page, pages int) {
+		if page < pages {
+			h.Set("Link", fmt.Sprintf(`<videos?page=%d>; rel=next`, page+1))
+		}
+	})
+
+	ids, err := fetchIDs(t, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, wantIDs(5)) {
+		t.Errorf("got %v, want %v", ids, wantIDs(5))
+	}
+}
+
+func TestListPagesStopsAtLinkLoop(t *testing.T) {
+	srv := newPagedServer(t, 4, 2, func(h http.Header, page, _ int) {
+		next := "/api/v1/browse/channels/ch1/videos?page=2"
+		if page == 2 {
+			next = "/api/v1/browse/channels/ch1/videos" // back to the first page
+		}
+		h.Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next))
+	})
+
+	ids, err := fetchIDs(t, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, wantIDs(4)) {
+		t.Errorf("got %v, want %v", ids, wantIDs(4))
+	}
+}
+
+func TestListPagesRefusesForeignLink(t *testing.T) {
+	var gotToken bool
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get("Authorization") != ""
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer foreign.Close()
+	srv := newPagedServer(t, 4, 2, func(h http.Header, page, _ int) {
+		if page == 1 {
+			h.Set("Link", fmt.Sprintf(`<%s/videos?page=2>; rel="next"`, foreign.URL))
+		}
+	})
+
+	_, err := fetchIDs(t, srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "refusing next page link") {
+		t.Errorf("got error %v, want a refused link", err)
+	}
+	if gotToken {
+		t.Error("the access token was sent to another host")
+	}
+}
+
+func TestListPagesUsesPageParameter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func(h http.Header, page, pages int)
+	}{
+		{"X-Total-Pages", func(h http.Header, _, pages int) {
+			h.Set("X-Total-Pages", strconv.Itoa(pages))
+		}},
+		{"X-Next-Page", func(h http.Header, page, pages int) {
+			if page < pages {
+				h.Set("X-Next-Page", strconv.Itoa(page+1))
+			} else {
+				h.Set("X-Next-Page", "")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPagedServer(t, 8, 3, tt.header)
+			ids, err := fetchIDs(t, srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, wantIDs(8)) {
+				t.Errorf("got %v, want %v", ids, wantIDs(8))
+			}
+		})
+	}
+}
+
+func TestListPagesSinglePage(t *testing.T) {
+	srv := newPagedServer(t, 3, 10, func(http.Header, int, int) {})
+
+	ids, err := fetchIDs(t, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, wantIDs(3)) {
+		t.Errorf("got %v, want %v", ids, wantIDs(3))
+	}
+}
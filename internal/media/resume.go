@@ -0,0 +1,185 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	partSuffix     = ".part"
+	partMetaSuffix = ".part.json"
+)
+
+// partialDownload is persisted next to a .part file. The validators make sure a
+// resumed download is only continued if the file on the server is unchanged.
+type partialDownload struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	TotalSize    int64  `json:"total_size,omitempty"`
+	Segmented    bool   `json:"segmented,omitempty"` // Written out of order, cannot be continued linearly
+}
+
+func partPath(outputFile string) string {
+	return outputFile + partSuffix
+}
+
+func partMetaPath(outputFile string) string {
+	return outputFile + partMetaSuffix
+}
+
+func partialFromResponse(resp *http.Response, totalSize int64) *partialDownload {
+	return &partialDownload{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		TotalSize:    totalSize,
+	}
+}
+
+// ifRangeValidator returns the value for the If-Range header. Weak ETags are
+// not allowed in If-Range, so Last-Modified is used as fallback.
+func (p *partialDownload) ifRangeValidator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+// matches reports whether a 206 response belongs to the same file as the stored part.
+func (p *partialDownload) matches(resp *http.Response, total int64) bool {
+	if p.TotalSize > 0 && total > 0 && p.TotalSize != total {
+		return false
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" && p.ETag != "" && etag != p.ETag {
+		return false
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" && p.LastModified != "" && lm != p.LastModified {
+		return false
+	}
+	return true
+}
+
+// loadPartialDownload returns the size of an existing .part file and its
+// metadata. A part without usable validators cannot be resumed safely and is
+// reported with an offset of 0.
+func loadPartialDownload(outputFile string) (int64, *partialDownload) {
+	info, err := os.Stat(partPath(outputFile))
+	if err != nil || info.Size() == 0 {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(partMetaPath(outputFile))
+	if err != nil {
+		return 0, nil
+	}
+	var meta partialDownload
+	if err = json.Unmarshal(data, &meta); err != nil || meta.Segmented || meta.ifRangeValidator() == "" {
+		return 0, nil
+	}
+	if meta.TotalSize > 0 && info.Size() > meta.TotalSize {
+		return 0, nil
+	}
+	return info.Size(), &meta
+}
+
+func savePartialDownload(outputFile string, meta *partialDownload) error {
+	metaFile := partMetaPath(outputFile)
+	if meta.ifRangeValidator() == "" { // nothing to validate a resume against
+		if err := os.Remove(metaFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale resume metadata: %w", err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode resume metadata: %w", err)
+	}
+	if err = os.WriteFile(metaFile, data, DefaultFilePermissions); err != nil {
+		return fmt.Errorf("failed to write resume metadata: %w", err)
+	}
+	return nil
+}
+
+// commitPartialDownload flushes the .part file to disk, checks its size and
+// content and atomically renames it to outputFile. A part of the wrong size or
+// failing verify is removed, so the next attempt starts from scratch.
+// expectedSize 0 and a nil verify skip the respective check.
+func commitPartialDownload(outputFile string, expectedSize int64, verify func(path string) error) error {
+	part, err := os.OpenFile(partPath(outputFile), os.O_WRONLY, DefaultFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to open downloaded file: %w", err)
+	}
+	info, err := part.Stat()
+	if err == nil && expectedSize > 0 && info.Size() != expectedSize {
+		err = fmt.Errorf("downloaded file has %d bytes, expected %d", info.Size(), expectedSize)
+		_ = part.Close()
+		return errors.Join(err, removePartialDownload(outputFile))
+	}
+	if err == nil {
+		err = part.Sync()
+	}
+	if cerr := part.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to flush downloaded file: %w", err)
+	}
+
+	if verify != nil {
+		if err = verify(partPath(outputFile)); err != nil {
+			err = fmt.Errorf("downloaded file is corrupt: %w", err)
+			return errors.Join(err, removePartialDownload(outputFile))
+		}
+	}
+
+	if err = os.Rename(partPath(outputFile), outputFile); err != nil {
+		return fmt.Errorf("failed to move downloaded file into place: %w", err)
+	}
+	if err = os.Remove(partMetaPath(outputFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove resume metadata: %w", err)
+	}
+	return nil
+}
+
+func removePartialDownload(outputFile string) error {
+	for _, name := range []string{partPath(outputFile), partMetaPath(outputFile)} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove partial download %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseContentRange parses a header of the form "bytes start-end/total".
+// The total is -1 if the server reports it as unknown ("*").
+func parseContentRange(header string) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range header: %q", header)
+	}
+	rangePart, totalPart, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range header: %q", header)
+	}
+	startPart, _, ok := strings.Cut(rangePart, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range header: %q", header)
+	}
+
+	start, err := strconv.ParseInt(startPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range start: %q", header)
+	}
+	if totalPart == "*" {
+		return start, -1, nil
+	}
+	total, err := strconv.ParseInt(totalPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range total: %q", header)
+	}
+	return start, total, nil
+}
@@ -0,0 +1,191 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var remoteFile = bytes.Repeat([]byte("0123456789"), 100)
+
+// startPartialDownload writes the first size bytes of remoteFile as .part file
+// with the resume metadata of a previous attempt.
+func startPartialDownload(t *testing.T, size int) string {
+	t.Helper()
+	outputFile := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(partPath(outputFile), remoteFile[:size], DefaultFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+	meta := &partialDownload{ETag: `"v1"`, TotalSize: int64(len(remoteFile))}
+	if err := savePartialDownload(outputFile, meta); err != nil {
+		t.Fatal(err)
+	}
+	return outputFile
+}
+
+func TestDownloadFileFromURLResume(t *testing.T) {
+	const partSize = 400
+	serveFull := func(w http.ResponseWriter) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(remoteFile)))
+		_, _ = w.Write(remoteFile)
+	}
+	tests := []struct {
+		name       string
+		partSize   int
+		handler    func(w http.ResponseWriter, r *http.Request)
+		wantRanges []string // Range headers of the requests
+	}{
+		{
+			name:     "206 with matching range",
+			partSize: partSize,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(remoteFile))
+			},
+			wantRanges: []string{"bytes=400-"},
+		},
+		{
+			name:     "200 ignoring the range",
+			partSize: partSize,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				serveFull(w)
+			},
+			wantRanges: []string{"bytes=400-"},
+		},
+		{
+			name:     "416 on a complete part",
+			partSize: len(remoteFile),
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			},
+			wantRanges: []string{"bytes=1000-"},
+		},
+		{
+			name:     "mismatched Content-Range",
+			partSize: partSize,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "" {
+					serveFull(w)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 500-999/%d", len(remoteFile)))
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(remoteFile[500:])
+			},
+			wantRanges: []string{"bytes=400-", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				tt.handler(w, r)
+			}))
+			defer srv.Close()
+
+			c := NewClient("token")
+			c.Out = io.Discard
+			outputFile := startPartialDownload(t, tt.partSize)
+			ctx := context.Background()
+			p := c.newDownloadProgress(ctx)
+
+			_, err := c.downloadFileFromURL(ctx, p, srv.URL+"/video.mp4", &downloadJob{outputFile: outputFile})
+			p.container.Wait()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ranges, tt.wantRanges) {
+				t.Errorf("requested ranges %q, want %q", ranges, tt.wantRanges)
+			}
+			data, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, remoteFile) {
+				t.Errorf("downloaded %d bytes differing from the remote file", len(data))
+			}
+			for _, name := range []string{partPath(outputFile), partMetaPath(outputFile)} {
+				if _, err = os.Stat(name); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s was not removed: %v", filepath.Base(name), err)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchToPartFileRejectsMismatchedResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentRange string
+		etag         string
+	}{
+		{"other start", "bytes 500-999/1000", `"v1"`},
+		{"other total", "bytes 400-1199/1200", `"v1"`},
+		{"other ETag", "bytes 400-999/1000", `"v2"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("ETag", tt.etag)
+				w.Header().Set("Content-Range", tt.contentRange)
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(remoteFile[400:])
+			}))
+			defer srv.Close()
+
+			c := NewClient("token")
+			c.Out = io.Discard
+			outputFile := startPartialDownload(t, 400)
+			ctx := context.Background()
+			p := c.newDownloadProgress(ctx)
+
+			_, err := c.fetchToPartFile(ctx, p, srv.URL, outputFile)
+			p.container.Wait()
+			if !errors.Is(err, errResumeMismatch) {
+				t.Errorf("got error %v, want %v", err, errResumeMismatch)
+			}
+			if data, _ := os.ReadFile(partPath(outputFile)); !bytes.Equal(data, remoteFile[:400]) {
+				t.Errorf("part file was changed to %d bytes", len(data))
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header    string
+		wantStart int64
+		wantTotal int64
+		wantErr   bool
+	}{
+		{"bytes 400-999/1000", 400, 1000, false},
+		{"bytes 0-0/1", 0, 1, false},
+		{"bytes 400-999/*", 400, -1, false},
+		{"", 0, 0, true},
+		{"items 400-999/1000", 0, 0, true},
+		{"bytes 400-999", 0, 0, true},
+		{"bytes 400/1000", 0, 0, true},
+		{"bytes x-999/1000", 0, 0, true},
+		{"bytes 400-999/x", 0, 0, true},
+	}
+	for _, tt := range tests {
+		start, total, err := parseContentRange(tt.header)
+		if (err != nil) != tt.wantErr || start != tt.wantStart || total != tt.wantTotal {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v, want %d, %d, error %t",
+				tt.header, start, total, err, tt.wantStart, tt.wantTotal, tt.wantErr)
+		}
+	}
+}
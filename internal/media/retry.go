@@ -0,0 +1,129 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = time.Second
+	maxRetryDelay     = 30 * time.Second
+	maxRetryAfter     = 2 * time.Minute // upper bound for server provided Retry-After values
+)
+
+// RetryPolicy controls how often and how fast failed requests are repeated.
+// Only transient errors of idempotent requests (GET, HEAD) are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first one
+	BaseDelay   time.Duration // Delay before the first retry, doubled for each further retry
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetries + 1,
+		BaseDelay:   DefaultRetryDelay,
+		MaxDelay:    maxRetryDelay,
+	}
+}
+
+// withRetry calls fn until it succeeds, fails with a permanent error or the
+// attempts are used up. It returns the number of attempts made.
+func (c *Client) withRetry(ctx context.Context, operation string, fn func() error) (int, error) {
+	maxAttempts := max(c.Retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || !isRetryable(ctx, err) {
+			return attempt, err
+		}
+
+		delay := c.Retry.backoff(attempt, err)
+		c.Logger.DebugContext(ctx, "request failed, retrying",
+			"operation", operation,
+			"attempt", attempt,
+			"max_attempts", maxAttempts,
+			"delay", delay,
+			"error", err,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt: a server provided
+// Retry-After value, otherwise exponential backoff with jitter.
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, maxRetryAfter)
+	}
+
+	delay := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// "equal jitter": at least half of the delay, so retries never hammer the server
+	return delay/2 + rand.N(delay/2+1) //nolint:gosec // jitter does not need a secure source
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	// Other network errors, e.g. an unknown host or a rejected certificate, are permanent
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package media
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// transportError wraps err like the HTTP client wraps a failed request.
+func transportError(err error) error {
+	return &url.Error{Op: "Get", URL: "https://tube.switch.ch/api/v1/profiles/me", Err: err}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"request timeout", &APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{"not found", &APIError{StatusCode: http.StatusNotFound}, false},
+		{"unauthorized", fmt.Errorf("fetch failed: %w", &APIError{StatusCode: http.StatusUnauthorized}), false},
+		{"unexpected EOF", fmt.Errorf("download failed: %w", io.ErrUnexpectedEOF), true},
+		{"connection reset", transportError(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"connection refused", transportError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"timeout", transportError(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}), true},
+		{"DNS timeout", transportError(&net.OpError{Op: "dial", Err: &net.DNSError{Name: "tube.switch.ch", IsTimeout: true}}), true},
+		{"unknown host", transportError(&net.OpError{Op: "dial", Err: &net.DNSError{Name: "tube.swich.ch", IsNotFound: true}}), false},
+		{"rejected certificate", transportError(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), false},
+		{"TLS alert", transportError(&net.OpError{Op: "remote error", Err: tls.AlertError(40)}), false},
+		{"other error", errors.New("invalid character"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(context.Background(), tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if isRetryable(ctx, io.ErrUnexpectedEOF) {
+		t.Error("errors are retried after the context was canceled")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0},
+		{"soon", 0},
+		{"1.5", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		{"first retry", policy, 1, io.ErrUnexpectedEOF, 500 * time.Millisecond, time.Second},
+		{"doubled", policy, 3, io.ErrUnexpectedEOF, 2 * time.Second, 4 * time.Second},
+		{"capped", policy, 4, io.ErrUnexpectedEOF, 2500 * time.Millisecond, 5 * time.Second},
+		{"capped on overflow", policy, 100, io.ErrUnexpectedEOF, 2500 * time.Millisecond, 5 * time.Second},
+		{"no delay", RetryPolicy{}, 2, io.ErrUnexpectedEOF, 0, 0},
+		{"Retry-After", policy, 1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 10 * time.Second},
+			10 * time.Second, 10 * time.Second},
+		{"Retry-After capped", policy, 1, &APIError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Hour},
+			maxRetryAfter, maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[time.Duration]bool{}
+			for range 100 {
+				delay := tt.policy.backoff(tt.attempt, tt.err)
+				if delay < tt.min || delay > tt.max {
+					t.Fatalf("delay %s is not within %s and %s", delay, tt.min, tt.max)
+				}
+				seen[delay] = true
+			}
+			if tt.min != tt.max && len(seen) < 2 {
+				t.Error("delay has no jitter")
+			}
+		})
+	}
+}
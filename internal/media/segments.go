@@ -0,0 +1,194 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// minSegmentSize avoids splitting small files into many tiny requests.
+const minSegmentSize = 4 << 20 // 4 MiB
+
+var errSegmentedUnavailable = errors.New("segmented download not possible")
+
+// fetchSegmented downloads the file in the given number of byte ranges in
+// parallel and writes them into a preallocated .part file. It returns
+// errSegmentedUnavailable if the server does not support range requests, and
+// otherwise the highest number of attempts any segment needed.
+func (c *Client) fetchSegmented(
+	ctx context.Context,
+	p *downloadProgress,
+	downloadURL, outputFile string,
+	segments int,
+) (_ int, err error) {
+	if offset, _ := loadPartialDownload(outputFile); offset > 0 {
+		return 0, errSegmentedUnavailable // continuing the single stream is cheaper
+	}
+
+	var meta *partialDownload
+	if _, err = c.withRetry(ctx, "probe "+downloadURL, func() error {
+		var probeErr error
+		meta, probeErr = c.probeRanges(ctx, downloadURL)
+		return probeErr
+	}); err != nil {
+		return 0, err
+	}
+	segments = int(min(int64(segments), meta.TotalSize/minSegmentSize))
+	if segments < 2 { //nolint:mnd // a single segment is a regular download
+		return 0, errSegmentedUnavailable
+	}
+
+	out, err := os.Create(partPath(outputFile))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		if cerr := out.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close output file: %w", cerr)
+		}
+	}()
+	if err = out.Truncate(meta.TotalSize); err != nil {
+		return 0, fmt.Errorf("failed to preallocate output file: %w", err)
+	}
+	if err = savePartialDownload(outputFile, meta); err != nil {
+		return 0, err
+	}
+
+	bar := newDownloadBar(p, filepath.Base(outputFile), 0, meta.TotalSize)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	segmentSize := meta.TotalSize / int64(segments)
+	errs := make([]error, segments)
+	attempts := make([]int, segments)
+	var wg sync.WaitGroup
+	for i := range segments {
+		start := int64(i) * segmentSize
+		end := start + segmentSize - 1
+		if i == segments-1 {
+			end = meta.TotalSize - 1
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts[i], errs[i] = c.fetchSegment(ctx, downloadURL, meta, start, end, bar, out)
+			if errs[i] != nil {
+				cancel() // no point in continuing the other segments
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err = errors.Join(errs...); err != nil {
+		bar.Abort(false)
+		return slices.Max(attempts), err
+	}
+	bar.SetTotal(-1, true)
+	return slices.Max(attempts), nil
+}
+
+// probeRanges checks with a HEAD request whether the server accepts byte
+// ranges and returns the size and validators of the file.
+func (c *Client) probeRanges(ctx context.Context, downloadURL string) (*partialDownload, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, downloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe download: %w", err)
+	}
+	if err = resp.Body.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK ||
+		!strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") ||
+		resp.ContentLength <= 0 {
+		return nil, errSegmentedUnavailable
+	}
+
+	meta := partialFromResponse(resp, resp.ContentLength)
+	meta.Segmented = true
+	return meta, nil
+}
+
+// fetchSegment downloads the bytes start to end into out. A retried attempt
+// continues after the bytes the previous attempt already wrote.
+func (c *Client) fetchSegment(
+	ctx context.Context,
+	downloadURL string,
+	meta *partialDownload,
+	start, end int64,
+	bar *downloadBar,
+	out io.WriterAt,
+) (int, error) {
+	var written int64
+	return c.withRetry(ctx, "download segment", func() error {
+		offset := start + written
+		n, err := c.fetchRange(ctx, downloadURL, meta, offset, end, bar, io.NewOffsetWriter(out, offset))
+		written += n
+		return err
+	})
+}
+
+func (c *Client) fetchRange(
+	ctx context.Context,
+	downloadURL string,
+	meta *partialDownload,
+	start, end int64,
+	bar *downloadBar,
+	out io.Writer,
+) (_ int64, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if validator := meta.ifRangeValidator(); validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download segment: %w", err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close response body: %w", cerr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("segment %d-%d failed: %w", start, end, newAPIError(resp))
+	}
+	rangeStart, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return 0, err
+	}
+	if rangeStart != start || !meta.matches(resp, total) {
+		return 0, fmt.Errorf("segment %d-%d does not match the remote file", start, end)
+	}
+
+	reader := bar.ProxyReader(resp.Body)
+	defer func() {
+		if cerr := reader.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close reader: %w", cerr)
+		}
+	}()
+	n, err := io.CopyN(out, reader, end-start+1)
+	if err != nil {
+		return n, fmt.Errorf("failed to write segment %d-%d: %w", start, end, err)
+	}
+	return n, nil
+}
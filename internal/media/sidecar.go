@@ -0,0 +1,92 @@
+package media
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	infoJSONSuffix = ".info.json"
+	nfoSuffix      = ".nfo"
+)
+
+// videoMetadata is the content of the .info.json sidecar.
+type videoMetadata struct {
+	VideoDetails
+	Channel      string        `json:"channel,omitempty"`
+	Variant      *VideoVariant `json:"variant,omitempty"`
+	URL          string        `json:"url"`
+	DownloadedAt time.Time     `json:"downloaded_at"`
+}
+
+// nfoMovie is the subset of the Kodi movie NFO format that SwitchTube can fill.
+// Jellyfin reads the same format.
+type nfoMovie struct {
+	XMLName   xml.Name    `xml:"movie"`
+	Title     string      `xml:"title"`
+	Studio    string      `xml:"studio,omitempty"`
+	Premiered string      `xml:"premiered,omitempty"`
+	Runtime   int         `xml:"runtime,omitempty"` // In minutes
+	UniqueID  nfoUniqueID `xml:"uniqueid"`
+}
+
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	ID      string `xml:",chardata"`
+}
+
+// sidecarPaths returns the paths of all sidecar files that may belong to
+// outputFile, including its extracted audio.
+func sidecarPaths(outputFile string) []string {
+	base := strings.TrimSuffix(outputFile, filepath.Ext(outputFile))
+	return []string{base + infoJSONSuffix, base + nfoSuffix, base + audioExtension}
+}
+
+// writeSidecars writes the metadata files requested for the job next to its output file.
+func (c *Client) writeSidecars(job *downloadJob, variant *VideoVariant) error {
+	base := strings.TrimSuffix(job.outputFile, filepath.Ext(job.outputFile))
+
+	if job.writeInfoJSON {
+		metadata := videoMetadata{
+			VideoDetails: *job.details,
+			Channel:      job.channel,
+			Variant:      variant,
+			URL:          c.videoPageURL(job.videoID),
+			DownloadedAt: time.Now().UTC(),
+		}
+		data, err := json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode video metadata: %w", err)
+		}
+		if err = os.WriteFile(base+infoJSONSuffix, data, DefaultFilePermissions); err != nil {
+			return fmt.Errorf("failed to write %s: %w", infoJSONSuffix, err)
+		}
+	}
+
+	if job.writeNFO {
+		movie := nfoMovie{
+			Title:    job.details.Title,
+			Studio:   job.channel,
+			Runtime:  int((time.Duration(job.details.DurationInMilliseconds) * time.Millisecond).Round(time.Minute).Minutes()),
+			UniqueID: nfoUniqueID{Type: "switchtube", Default: true, ID: job.videoID},
+		}
+		if published, err := time.Parse(time.RFC3339, job.details.PublishedAt); err == nil {
+			movie.Premiered = published.Format(time.DateOnly)
+		}
+		data, err := xml.MarshalIndent(movie, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode NFO: %w", err)
+		}
+		data = append([]byte(xml.Header), data...)
+		if err = os.WriteFile(base+nfoSuffix, append(data, '\n'), DefaultFilePermissions); err != nil {
+			return fmt.Errorf("failed to write %s: %w", nfoSuffix, err)
+		}
+	}
+	return nil
+}
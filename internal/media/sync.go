@@ -0,0 +1,227 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	syncStateFile = ".switchdl-sync.json"
+	removedDir    = ".removed"
+)
+
+// syncState is stored in every synced channel directory. It remembers which
+// videos were downloaded, so later runs only fetch new or republished videos.
+type syncState struct {
+	ChannelID string                 `json:"channel_id"`
+	SyncedAt  time.Time              `json:"synced_at"`
+	Videos    map[string]syncedVideo `json:"videos"`
+}
+
+type syncedVideo struct {
+	Title       string `json:"title"`
+	PublishedAt string `json:"published_at"`
+	File        string `json:"file,omitempty"` // Relative to the channel directory
+}
+
+type SyncReport struct {
+	ChannelName string
+	Directory   string
+	Added       []string // Titles of newly downloaded videos
+	Skipped     []string // Titles of new videos that were already downloaded (existing file or download archive)
+	Updated     []string // Titles of republished videos that were downloaded again
+	Removed     []string // Titles of videos no longer in the channel
+	Failed      []DownloadResult
+}
+
+// SyncChannel mirrors cfg.ChannelID into a subdirectory of cfg.OutputDir.
+// Only videos that are new or were republished since the last sync are downloaded.
+func (c *Client) SyncChannel(ctx context.Context, cfg *DownloadConfig) (*SyncReport, error) {
+	channelDetails, err := c.fetchChannelDetails(ctx, cfg.ChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch channel details: %w", err)
+	}
+
+	channelVideos, err := c.fetchChannelVideos(ctx, cfg.ChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch channel videos: %w", err)
+	}
+
+	channelDir := filepath.Join(cfg.OutputDir, sanitizeFilename(channelDetails.Name))
+	if err = os.MkdirAll(channelDir, DefaultDirectoryPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create channel directory: %w", err)
+	}
+
+	state, err := loadSyncState(channelDir, cfg.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]ChannelVideo, len(channelVideos))
+	videoOptions := make(map[string]VideoOptions, len(channelVideos))
+	var newIDs, updatedIDs []string
+	for i, v := range channelVideos {
+		listed[v.ID] = v
+		videoOptions[v.ID] = VideoOptions{Index: i + 1}
+		known, ok := state.Videos[v.ID]
+		switch {
+		case !ok:
+			newIDs = append(newIDs, v.ID)
+		case v.PublishedAt != "" && v.PublishedAt != known.PublishedAt:
+			updatedIDs = append(updatedIDs, v.ID)
+		}
+	}
+
+	fmt.Fprintf(c.Out, "Syncing channel '%s' to '%s': %d new, %d republished video(s)\n",
+		channelDetails.Name, channelDir, len(newIDs), len(updatedIDs))
+
+	syncCfg := *cfg
+	syncCfg.ChannelName = channelDetails.Name
+	syncCfg.Videos = videoOptions
+
+	report := &SyncReport{ChannelName: channelDetails.Name, Directory: channelDir}
+	report.Added, report.Skipped, report.Failed = c.syncVideos(ctx, &syncCfg, channelDir, newIDs, false, state, listed)
+	updated, _, failed := c.syncVideos(ctx, &syncCfg, channelDir, updatedIDs, true, state, listed)
+	report.Updated = updated
+	report.Failed = append(report.Failed, failed...)
+
+	for id, known := range state.Videos {
+		if _, ok := listed[id]; ok {
+			continue
+		}
+		report.Removed = append(report.Removed, known.Title)
+		if !cfg.MoveRemoved {
+			continue
+		}
+		if err = moveToRemoved(channelDir, known.File); err != nil {
+			return nil, err
+		}
+		delete(state.Videos, id)
+	}
+	slices.Sort(report.Removed)
+
+	state.SyncedAt = time.Now().UTC()
+	if err = state.save(channelDir); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// syncVideos downloads the videos into channelDir and records the successful
+// ones in the state. Republished videos overwrite their previous file. Videos
+// skipped because they were already downloaded are recorded too, but returned
+// separately from the downloaded ones.
+func (c *Client) syncVideos(
+	ctx context.Context,
+	cfg *DownloadConfig,
+	channelDir string,
+	videoIDs []string,
+	republished bool,
+	state *syncState,
+	listed map[string]ChannelVideo,
+) (downloaded, skipped []string, failed []DownloadResult) {
+	if len(videoIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	// the template is applied relative to the channel directory, which holds the sync state
+	videoCfg := *cfg
+	videoCfg.OutputDir = channelDir
+	videoCfg.Overwrite = republished || cfg.Overwrite
+	videoCfg.Skip = !republished && !cfg.Overwrite // adopt files downloaded before the first sync
+	videoCfg.SelectVariant = false
+	videoCfg.Filename = ""
+	videoCfg.VideoIDs = videoIDs
+	if republished { // the archive would skip republished videos
+		videoCfg.Archive = nil
+	}
+	summary := c.DownloadVideos(ctx, &videoCfg)
+
+	for _, result := range summary.Results {
+		if result.Error != nil {
+			failed = append(failed, result)
+			continue
+		}
+
+		video := listed[result.VideoID]
+		entry := syncedVideo{Title: video.Title, PublishedAt: video.PublishedAt}
+		if result.OutputFile != "" {
+			entry.File, _ = filepath.Rel(channelDir, result.OutputFile)
+		}
+		if previous, ok := state.Videos[result.VideoID]; ok && previous.File != entry.File && cfg.MoveRemoved {
+			if err := moveToRemoved(channelDir, previous.File); err != nil {
+				fmt.Fprintf(c.Out, "Warning: %v\n", err)
+			}
+		}
+		state.Videos[result.VideoID] = entry
+		if result.Skipped {
+			skipped = append(skipped, video.Title)
+		} else {
+			downloaded = append(downloaded, video.Title)
+		}
+	}
+	return downloaded, skipped, failed
+}
+
+func loadSyncState(channelDir, channelID string) (*syncState, error) {
+	state := &syncState{ChannelID: channelID, Videos: make(map[string]syncedVideo)}
+
+	data, err := os.ReadFile(filepath.Join(channelDir, syncStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state %s: %w", syncStateFile, err)
+	}
+	if state.ChannelID != channelID {
+		return nil, fmt.Errorf("directory %s is synced with channel %s, not %s", channelDir, state.ChannelID, channelID)
+	}
+	if state.Videos == nil {
+		state.Videos = make(map[string]syncedVideo)
+	}
+	return state, nil
+}
+
+func (s *syncState) save(channelDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+	if err = os.WriteFile(filepath.Join(channelDir, syncStateFile), data, DefaultFilePermissions); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return nil
+}
+
+// moveToRemoved moves a file of a video that is no longer in the channel, and
+// its sidecar files, into the .removed folder of the channel directory,
+// keeping their relative paths.
+func moveToRemoved(channelDir, file string) error {
+	if file == "" {
+		return nil
+	}
+	for _, name := range append([]string{file}, sidecarPaths(file)...) {
+		src := filepath.Join(channelDir, name)
+		if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		dst := filepath.Join(channelDir, removedDir, name)
+		if err := os.MkdirAll(filepath.Dir(dst), DefaultDirectoryPermissions); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", removedDir, err)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", name, removedDir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newChannelServer serves channel ch1 with the videos v1, v2 and v3, each
+// with a single MP4 variant.
+func newChannelServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	encode := func(w http.ResponseWriter, v any) {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			t.Error(err)
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == "/api/v1/browse/channels/ch1":
+			encode(w, ChannelDetails{ID: "ch1", Name: "Channel"})
+		case path == "/api/v1/browse/channels/ch1/videos":
+			encode(w, []ChannelVideo{
+				{ID: "v1", Title: "Video v1", PublishedAt: "2025-06-01T10:00:00Z"},
+				{ID: "v2", Title: "Video v2", PublishedAt: "2025-06-02T10:00:00Z"},
+				{ID: "v3", Title: "Video v3", PublishedAt: "2025-06-03T10:00:00Z"},
+			})
+		case strings.HasSuffix(path, "/video_variants"):
+			id := strings.Split(path, "/")[5]
+			encode(w, []VideoVariant{{Path: "/media/" + id, Name: "HD", MediaType: "video/mp4"}})
+		case strings.HasPrefix(path, "/api/v1/browse/videos/"):
+			id := strings.Split(path, "/")[5]
+			encode(w, VideoDetails{ID: id, Title: "Video " + id, PublishedAt: "2025-06-01T10:00:00Z", DurationInMilliseconds: 60000})
+		case strings.HasPrefix(path, "/media/"):
+			_, _ = w.Write(movieFile(60000))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSyncChannelReportsSkippedVideos(t *testing.T) {
+	srv := newChannelServer(t)
+	c := NewClient("token")
+	c.BaseURL = srv.URL
+	c.Out = io.Discard
+
+	dir := t.TempDir()
+	archive, err := OpenDownloadArchive(filepath.Join(dir, "archive.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = archive.Record("v3", "HD"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &DownloadConfig{ChannelID: "ch1", OutputDir: dir, Jobs: 2, Archive: archive}
+	channelDir := filepath.Join(dir, "Channel")
+
+	report, err := c.SyncChannel(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed) > 0 {
+		t.Fatalf("failed downloads: %v", report.Failed)
+	}
+	if want := []string{"Video v1", "Video v2"}; !slices.Equal(report.Added, want) {
+		t.Errorf("added %v, want %v", report.Added, want)
+	}
+	if want := []string{"Video v3"}; !slices.Equal(report.Skipped, want) {
+		t.Errorf("skipped %v, want %v", report.Skipped, want)
+	}
+
+	state, err := loadSyncState(channelDir, "ch1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file := state.Videos["v3"].File; file != "" {
+		t.Errorf("archived video is recorded with file %q", file)
+	}
+	if len(state.Videos) != 3 || state.Videos["v1"].File == "" {
+		t.Errorf("sync state holds %v, want all three videos with v1 downloaded", state.Videos)
+	}
+
+	// Without the state file, the downloaded files are adopted instead of added again
+	if err = os.Remove(filepath.Join(channelDir, syncStateFile)); err != nil {
+		t.Fatal(err)
+	}
+	report, err = c.SyncChannel(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added) > 0 {
+		t.Errorf("added %v, want none", report.Added)
+	}
+	if want := []string{"Video v1", "Video v2", "Video v3"}; !slices.Equal(report.Skipped, want) {
+		t.Errorf("skipped %v, want %v", report.Skipped, want)
+	}
+}
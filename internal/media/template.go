@@ -0,0 +1,178 @@
+package media
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVideoTemplate   = "{title}"
+	defaultChannelTemplate = "{channel}/{title}"
+	defaultPublishedLayout = time.DateOnly
+	defaultDurationLayout  = "15h04m05s"
+)
+
+// templateValues are the values available to output templates.
+type templateValues struct {
+	Channel  string
+	Title    string
+	ID       string
+	Variant  string
+	Index    int
+	Details  *VideoDetails
+	Duration time.Duration
+}
+
+func newTemplateValues(
+	videoID string,
+	details *VideoDetails,
+	channel string,
+	variant *VideoVariant,
+	index int,
+) *templateValues {
+	values := &templateValues{
+		Channel:  channel,
+		Title:    details.Title,
+		ID:       videoID,
+		Index:    index,
+		Details:  details,
+		Duration: time.Duration(details.DurationInMilliseconds) * time.Millisecond,
+	}
+	if values.Title == "" {
+		values.Title = "video"
+	}
+	if variant != nil {
+		values.Variant = variant.Name
+	}
+	return values
+}
+
+// ValidateOutputTemplate reports unknown placeholders and invalid formats.
+// An empty template selects the default naming.
+func ValidateOutputTemplate(tmpl string) error {
+	if tmpl == "" {
+		return nil
+	}
+	_, err := renderOutputTemplate(tmpl, &templateValues{Title: "t", Details: &VideoDetails{}})
+	return err
+}
+
+// renderOutputTemplate expands placeholders like {title} or {published:2006-01-02}
+// into a relative file path without extension. Every value is sanitized, so
+// only the slashes of the template itself create subdirectories. A directory
+// or file name left empty or made of dots by its values, e.g. by an empty
+// title, is replaced by the video ID.
+func renderOutputTemplate(tmpl string, values *templateValues) (string, error) {
+	var parts []string
+	var b strings.Builder
+	expanded := false // whether the current part contains a value
+	endPart := func() error {
+		part := strings.TrimSpace(b.String())
+		if strings.Trim(part, ".") == "" {
+			if !expanded {
+				return fmt.Errorf("output template %q results in an invalid path", tmpl)
+			}
+			part = values.fallbackName()
+		}
+		parts = append(parts, part)
+		b.Reset()
+		expanded = false
+		return nil
+	}
+	writeLiteral := func(text string) error {
+		for {
+			before, after, found := strings.Cut(text, "/")
+			b.WriteString(before)
+			if !found {
+				return nil
+			}
+			if err := endPart(); err != nil {
+				return err
+			}
+			text = after
+		}
+	}
+
+	rest := tmpl
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			if err := writeLiteral(rest); err != nil {
+				return "", err
+			}
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed placeholder in output template %q", tmpl)
+		}
+		if err := writeLiteral(rest[:start]); err != nil {
+			return "", err
+		}
+
+		name, format, _ := strings.Cut(rest[start+1:start+end], ":")
+		value, err := values.expand(name, format)
+		if err != nil {
+			return "", fmt.Errorf("invalid output template %q: %w", tmpl, err)
+		}
+		b.WriteString(sanitizeFilename(value))
+		expanded = true
+		rest = rest[start+end+1:]
+	}
+	if err := endPart(); err != nil {
+		return "", err
+	}
+	return filepath.Join(parts...), nil
+}
+
+// fallbackName replaces names that would be empty or dots only.
+func (v *templateValues) fallbackName() string {
+	if id := sanitizeFilename(v.ID); strings.Trim(id, ".") != "" {
+		return id
+	}
+	return "video"
+}
+
+func (v *templateValues) expand(name, format string) (string, error) {
+	switch name {
+	case "channel":
+		if v.Channel == "" { // single videos are downloaded without channel details
+			return "unknown", nil
+		}
+		return v.Channel, nil
+	case "title":
+		return v.Title, nil
+	case "id":
+		return v.ID, nil
+	case "variant":
+		return v.Variant, nil
+	case "index":
+		if format == "" {
+			return strconv.Itoa(v.Index), nil
+		}
+		width, err := strconv.Atoi(format)
+		if err != nil || width < 0 {
+			return "", fmt.Errorf("invalid index width %q", format)
+		}
+		return fmt.Sprintf("%0*d", width, v.Index), nil
+	case "published":
+		if format == "" {
+			format = defaultPublishedLayout
+		}
+		published, err := time.Parse(time.RFC3339, v.Details.PublishedAt)
+		if err != nil {
+			return "unknown", nil //nolint:nilerr // keep the file name usable without a date
+		}
+		return published.Format(format), nil
+	case "duration":
+		if format == "" {
+			format = defaultDurationLayout
+		}
+		return time.Time{}.Add(v.Duration).Format(format), nil
+	default:
+		return "", fmt.Errorf("unknown placeholder {%s}", name)
+	}
+}
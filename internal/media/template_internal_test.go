@@ -0,0 +1,90 @@
+package media
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenderOutputTemplate(t *testing.T) {
+	details := &VideoDetails{Title: "Lecture 1", PublishedAt: "2025-06-02T11:08:32.977+02:00"}
+	values := func(change func(v *templateValues)) *templateValues {
+		v := &templateValues{
+			Channel:  "Algorithms",
+			Title:    details.Title,
+			ID:       "abc123",
+			Variant:  "1080p",
+			Index:    7,
+			Details:  details,
+			Duration: time.Hour + 2*time.Minute + 3*time.Second,
+		}
+		if change != nil {
+			change(v)
+		}
+		return v
+	}
+	tests := []struct {
+		name   string
+		tmpl   string
+		values *templateValues
+		want   string // empty if an error is expected
+	}{
+		{"channel", "{channel}", values(nil), "Algorithms"},
+		{"unknown channel", "{channel}", values(func(v *templateValues) { v.Channel = "" }), "unknown"},
+		{"title", "{title}", values(nil), "Lecture 1"},
+		{"id", "{id}", values(nil), "abc123"},
+		{"variant", "{variant}", values(nil), "1080p"},
+		{"index", "{index}", values(nil), "7"},
+		{"padded index", "{index:03}", values(nil), "007"},
+		{"published", "{published}", values(nil), "2025-06-02"},
+		{"published layout", "{published:02.01.2006}", values(nil), "02.06.2025"},
+		{"unknown publication date", "{published}", values(func(v *templateValues) { v.Details = &VideoDetails{} }), "unknown"},
+		{"duration", "{duration}", values(nil), "01h02m03s"},
+		{"duration layout", "{duration:15.04.05}", values(nil), "01.02.03"},
+		{"literal text and directories", "{channel}/{index:02} - {title}", values(nil), filepath.Join("Algorithms", "07 - Lecture 1")},
+		{"slash in a value", "{title}", values(func(v *templateValues) { v.Title = "Part 1/2" }), "Part 1_2"},
+		{"slash in a layout", "{published:2006/01}", values(nil), "2025_06"},
+		{"dots and slash in a value", "{title}", values(func(v *templateValues) { v.Title = "../../etc" }), ".._.._etc"},
+		{"invalid characters", "{title}", values(func(v *templateValues) { v.Title = `a:b*c?"d"` }), "a_b_c__d_"},
+		{"empty value", "{title}", values(func(v *templateValues) { v.Title = "" }), "abc123"},
+		{"whitespace value", "{title}", values(func(v *templateValues) { v.Title = "   " }), "abc123"},
+		{"dot-only value", "{title}", values(func(v *templateValues) { v.Title = ".." }), "abc123"},
+		{"dot-only directory", "{channel}/{title}", values(func(v *templateValues) { v.Channel = "." }), filepath.Join("abc123", "Lecture 1")},
+		{"no ID to fall back to", "{title}", values(func(v *templateValues) { v.Title, v.ID = "...", "" }), "video"},
+		{"unknown placeholder", "{name}", values(nil), ""},
+		{"unclosed placeholder", "{title", values(nil), ""},
+		{"invalid index width", "{index:x}", values(nil), ""},
+		{"empty directory in the template", "a//{title}", values(nil), ""},
+		{"parent directory in the template", "../{title}", values(nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderOutputTemplate(tt.tmpl, tt.values)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("got %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOutputTemplate(t *testing.T) {
+	for _, tmpl := range []string{"", "{title}", "{channel}/{index:03} - {title}", "{id}", "{variant}/{title}"} {
+		if err := ValidateOutputTemplate(tmpl); err != nil {
+			t.Errorf("%q: %v", tmpl, err)
+		}
+	}
+	for _, tmpl := range []string{"{name}", "{title", "/{title}", "{title}/..", "{index:-1}"} {
+		if err := ValidateOutputTemplate(tmpl); err == nil {
+			t.Errorf("%q: got no error", tmpl)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -13,17 +12,28 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
 )
 
+const progressBarWidth = 64
+
 func isInteractive() bool {
-	fi, err := os.Stdin.Stat()
+	return isTerminal(os.Stdin)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
 	return err == nil && (fi.Mode()&os.ModeCharDevice) != 0
 }
 
-func promptUser(prompt string) (string, error) {
-	fmt.Print(prompt)
+func (c *Client) promptUser(prompt string) (string, error) {
+	fmt.Fprint(c.Out, prompt)
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
@@ -32,16 +42,16 @@ func promptUser(prompt string) (string, error) {
 	return strings.TrimSpace(input), nil
 }
 
-func handleExistingOutputFile(outputFile string, cfg *DownloadConfig) (string, error) {
+func (c *Client) handleExistingOutputFile(outputFile string, cfg *DownloadConfig) (string, error) {
 	if cfg.Overwrite {
-		fmt.Printf("File %s already exists. Overwriting it.\n", outputFile)
+		fmt.Fprintf(c.Out, "File %s already exists. Overwriting it.\n", outputFile)
 		return outputFile, nil
 	}
 
 	_, statErr := os.Stat(outputFile)
 	if statErr == nil { // File exists
 		if cfg.Skip {
-			fmt.Printf("File %s already exists. Skipping download.\n", outputFile)
+			fmt.Fprintf(c.Out, "File %s already exists. Skipping download.\n", outputFile)
 			return "", nil
 		}
 
@@ -52,7 +62,7 @@ func handleExistingOutputFile(outputFile string, cfg *DownloadConfig) (string, e
 			)
 		}
 
-		return promptForFileAction(outputFile, cfg)
+		return c.promptForFileAction(outputFile)
 	} else if !os.IsNotExist(statErr) {
 		return "", fmt.Errorf("error checking output file %s: %w", outputFile, statErr)
 	}
@@ -60,9 +70,9 @@ func handleExistingOutputFile(outputFile string, cfg *DownloadConfig) (string, e
 	return outputFile, nil // file doesn't exist
 }
 
-func promptForFileAction(outputFile string, cfg *DownloadConfig) (string, error) {
+func (c *Client) promptForFileAction(outputFile string) (string, error) {
 	for {
-		choice, inputErr := promptUser(
+		choice, inputErr := c.promptUser(
 			fmt.Sprintf(
 				"Output file %s already exists.\n[O]verwrite / [R]ename / [S]kip? (o/r/s): ",
 				outputFile,
@@ -76,38 +86,74 @@ func promptForFileAction(outputFile string, cfg *DownloadConfig) (string, error)
 		case "o", "overwrite":
 			return outputFile, nil
 		case "r", "rename":
-			newPath, renameErr := promptForNewFilename(cfg)
+			newPath, renameErr := c.promptForNewFilename(filepath.Dir(outputFile), filepath.Ext(outputFile))
 			if renameErr != nil {
 				return "", renameErr
 			}
 			return newPath, nil
 		case "s", "skip":
-			fmt.Println("Skipping download.")
+			fmt.Fprintln(c.Out, "Skipping download.")
 			return "", nil
 		default:
-			fmt.Println("Invalid choice. Please enter o, r, or s.")
+			fmt.Fprintln(c.Out, "Invalid choice. Please enter o, r, or s.")
 		}
 	}
 }
 
-func promptForNewFilename(cfg *DownloadConfig) (string, error) {
+// promptForNewFilename asks for a file name in dir, adding ext if it's missing.
+func (c *Client) promptForNewFilename(dir, ext string) (string, error) {
 	for {
-		newName, inputErr := promptUser("Enter new filename: ")
+		newName, inputErr := c.promptUser("Enter new filename: ")
 		if inputErr != nil {
 			return "", fmt.Errorf("failed to read new filename: %w", inputErr)
 		}
 
-		newName = ensureMp4Suffix(newName)
-		newPath := filepath.Join(cfg.OutputDir, newName)
+		newName = ensureExtension(newName, ext)
+		newPath := filepath.Join(dir, newName)
 
 		if _, statErr := os.Stat(newPath); os.IsNotExist(statErr) {
 			return newPath, nil
 		}
-		fmt.Printf("File %s already exists. Please choose another name.\n", newName)
+		fmt.Fprintf(c.Out, "File %s already exists. Please choose another name.\n", newName)
+	}
+}
+
+// copyWithProgress writes body to out and shows its progress. offset is the
+// number of bytes already present in out from a previous attempt.
+func copyWithProgress(
+	p *downloadProgress,
+	name string,
+	body io.Reader,
+	out *os.File,
+	offset, totalSize int64,
+) (err error) {
+	bar := newDownloadBar(p, name, offset, totalSize)
+
+	reader := bar.ProxyReader(body)
+	defer func() {
+		if cerr := reader.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close reader: %w", cerr)
+		}
+	}()
+
+	if _, err = io.Copy(out, reader); err != nil {
+		bar.Abort(true) // a retry continues with a new bar
+		return fmt.Errorf("failed to write video to file: %w", err)
 	}
+
+	bar.SetTotal(-1, true) // completes bars of unknown size and short bodies
+	return nil
 }
 
-func copyWithProgress(ctx context.Context, resp *http.Response, out *os.File) (err error) {
+// shortenName cuts name to at most width terminal columns, ending it with
+// "..." if it is too long. Wide characters, e.g. CJK, take two columns.
+func shortenName(name string, width int) string {
+	return runewidth.Truncate(name, width, "...")
+}
+
+// newDownloadBar adds a bar named after the video to the shared progress
+// container. The bar starts at offset, so resumed downloads show their real progress.
+func newDownloadBar(p *downloadProgress, name string, offset, totalSize int64) *downloadBar {
 	const (
 		barStyleLBound     = "["
 		barStyleFiller     = "="
@@ -115,24 +161,13 @@ func copyWithProgress(ctx context.Context, resp *http.Response, out *os.File) (e
 		barStylePadding    = "-"
 		barStyleRBound     = "]"
 		decoratorSeparator = " | "
-		downloadMessage    = "Downloading:"
 		doneMessage        = "done"
 		unknownSizeMessage = " (unknown size)"
-		progressBarWidth   = 64
+		maxNameLength      = 40
 	)
 
-	contentLength := resp.Header.Get("Content-Length")
-	var totalSize int64
-	if contentLength != "" {
-		var parseErr error
-		totalSize, parseErr = strconv.ParseInt(contentLength, 10, 64)
-		if parseErr != nil {
-			// TODO: handle parseErr
-			totalSize = 0
-		}
-	}
+	nameDecorator := decor.Name(shortenName(name, maxNameLength), decor.WC{C: decor.DindentRight | decor.DextraSpace | decor.DSyncWidth})
 
-	p := mpb.NewWithContext(ctx, mpb.WithWidth(progressBarWidth))
 	barStyle := mpb.BarStyle().
 		Lbound(barStyleLBound).
 		Filler(barStyleFiller).
@@ -142,10 +177,10 @@ func copyWithProgress(ctx context.Context, resp *http.Response, out *os.File) (e
 
 	var bar *mpb.Bar
 	if totalSize > 0 {
-		bar = p.New(totalSize,
+		bar = p.container.New(totalSize,
 			barStyle,
 			mpb.PrependDecorators(
-				decor.Name(downloadMessage, decor.WC{C: decor.DindentRight | decor.DextraSpace}),
+				nameDecorator,
 				decor.OnComplete(decor.CountersKibiByte("% .2f / % .2f"), doneMessage),
 			),
 			mpb.AppendDecorators(
@@ -155,48 +190,39 @@ func copyWithProgress(ctx context.Context, resp *http.Response, out *os.File) (e
 			),
 		)
 	} else {
-		bar = p.New(0,
+		bar = p.container.New(0,
 			barStyle,
 			mpb.PrependDecorators(
-				decor.Name(downloadMessage, decor.WC{C: decor.DindentRight | decor.DextraSpace}),
+				nameDecorator,
 				decor.CountersKibiByte("% .2f"),
 			),
 			mpb.AppendDecorators(decor.Name(unknownSizeMessage)),
 		)
 	}
 
-	reader := bar.ProxyReader(resp.Body)
-	defer func() {
-		if cerr := reader.Close(); cerr != nil && err == nil {
-			err = fmt.Errorf("failed to close reader: %w", cerr)
-		}
-	}()
-
-	_, err = io.Copy(out, reader)
-	if err != nil {
-		return fmt.Errorf("failed to write video to file: %w", err)
+	if offset > 0 {
+		bar.SetCurrent(offset)
+		bar.DecoratorAverageAdjust(time.Now())
 	}
-
-	p.Wait()
-	fmt.Printf("Video \"%s\" downloaded successfully \n", out.Name())
-	return nil
+	p.transfer.start(offset, totalSize)
+	return &downloadBar{Bar: bar, transfer: p.transfer}
 }
 
-func selectVariantInteractively(variants []VideoVariant) (*VideoVariant, error) {
-	fmt.Println("\nAvailable video variants:")
+func (c *Client) selectVariantInteractively(variants []VideoVariant) (*VideoVariant, error) {
+	fmt.Fprintln(c.Out, "\nAvailable video variants:")
 	for i, v := range variants {
-		fmt.Printf("[%d] %s (%s)\n", i+1, v.Name, v.MediaType)
+		fmt.Fprintf(c.Out, "[%d] %s (%s)\n", i+1, v.Name, v.MediaType)
 	}
 
 	for {
-		choice, err := promptUser(fmt.Sprintf("\nSelect variant (1-%d): ", len(variants)))
+		choice, err := c.promptUser(fmt.Sprintf("\nSelect variant (1-%d): ", len(variants)))
 		if err != nil {
 			return nil, fmt.Errorf("failed to read user input: %w", err)
 		}
 
 		idx, err := strconv.Atoi(choice)
 		if err != nil || idx < 1 || idx > len(variants) {
-			fmt.Printf("Invalid choice. Please enter a number between 1 and %d.\n", len(variants))
+			fmt.Fprintf(c.Out, "Invalid choice. Please enter a number between 1 and %d.\n", len(variants))
 			continue
 		}
 
@@ -205,14 +231,14 @@ func selectVariantInteractively(variants []VideoVariant) (*VideoVariant, error)
 }
 
 func (c *Client) promptForQualitySelection(_ context.Context, cfg *DownloadConfig) (bool, error) {
-	fmt.Println("\nMultiple videos detected. How would you like to handle video quality selection?")
+	fmt.Fprintln(c.Out, "\nMultiple videos detected. How would you like to handle video quality selection?")
 
 	for {
-		choice, err := promptUser(
+		choice, err := c.promptUser(
 			"Select quality [I]ndividually for each video / Use [B]est quality for all (i/b): ",
 		)
 		if err != nil {
-			fmt.Println("Failed to read selection. Defaulting to best quality.")
+			fmt.Fprintln(c.Out, "Failed to read selection. Defaulting to best quality.")
 			cfg.SelectVariant = false
 			return false, err
 		}
@@ -222,25 +248,29 @@ func (c *Client) promptForQualitySelection(_ context.Context, cfg *DownloadConfi
 			return true, nil
 
 		case "b", "best":
-			fmt.Println("Using best quality for all videos.")
+			if cfg.Format != "" {
+				fmt.Fprintf(c.Out, "Using format %q for all videos.\n", cfg.Selector.String())
+			} else {
+				fmt.Fprintln(c.Out, "Using best quality for all videos.")
+			}
 			cfg.SelectVariant = false
 			return false, nil
 
 		default:
-			fmt.Println("Invalid choice. Please enter 'i' or 'b'.")
+			fmt.Fprintln(c.Out, "Invalid choice. Please enter 'i' or 'b'.")
 		}
 	}
 }
 
-func selectVideosInteractively(videos []*VideoDetails) ([]*VideoDetails, error) {
-	if err := displayVideosInTable(videos); err != nil {
+func (c *Client) selectVideosInteractively(videos []*VideoDetails) ([]*VideoDetails, error) {
+	if err := c.displayVideosInTable(videos); err != nil {
 		return nil, fmt.Errorf("failed to display videos: %w", err)
 	}
-	return promptForVideoSelection(videos)
+	return c.promptForVideoSelection(videos)
 }
 
-func displayVideosInTable(videos []*VideoDetails) error {
-	fmt.Println("\nAvailable videos:")
+func (c *Client) displayVideosInTable(videos []*VideoDetails) error {
+	fmt.Fprintln(c.Out, "\nAvailable videos:")
 
 	const (
 		minWidth      = 0
@@ -253,7 +283,7 @@ func displayVideosInTable(videos []*VideoDetails) error {
 		durationWidth = 10
 		dateWidth     = 12
 	)
-	writer := tabwriter.NewWriter(os.Stdout, minWidth, tabWidth, padding, padChar, flags)
+	writer := tabwriter.NewWriter(c.Out, minWidth, tabWidth, padding, padChar, flags)
 
 	if _, err := fmt.Fprintln(writer, "Index \t Title \t Duration \t Date"); err != nil {
 		return fmt.Errorf("failed to write table header: %w", err)
@@ -288,16 +318,16 @@ func formatVideoDetails(v *VideoDetails) (string, string) {
 	return formattedDuration, parsedTime.Format(time.DateOnly)
 }
 
-func promptForVideoSelection(videos []*VideoDetails) ([]*VideoDetails, error) {
+func (c *Client) promptForVideoSelection(videos []*VideoDetails) ([]*VideoDetails, error) {
 	for {
-		selection, err := promptUser("\nSelect videos (1,3-5,8,...) or 'a'/'all' for all: ")
+		selection, err := c.promptUser("\nSelect videos (1,3-5,8,...) or 'a'/'all' for all: ")
 		if err != nil {
 			return nil, fmt.Errorf("failed to read user input: %w", err)
 		}
 
 		trimmedSelection := strings.TrimSpace(selection)
 		if trimmedSelection == "" {
-			fmt.Println("Input cannot be empty. Please enter 'a'/'all' or a valid selection.")
+			fmt.Fprintln(c.Out, "Input cannot be empty. Please enter 'a'/'all' or a valid selection.")
 			continue
 		}
 
@@ -308,7 +338,7 @@ func promptForVideoSelection(videos []*VideoDetails) ([]*VideoDetails, error) {
 
 		selectedIndices, err := parseVideoSelection(trimmedSelection, len(videos))
 		if err != nil {
-			fmt.Printf("Invalid selection: %v. Try again.\n", err)
+			fmt.Fprintf(c.Out, "Invalid selection: %v. Try again.\n", err)
 			continue
 		}
 
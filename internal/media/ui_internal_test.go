@@ -0,0 +1,29 @@
+package media
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestShortenName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Lecture 1.mp4", "Lecture 1.mp4"},
+		{"Einführung in die Programmierung.mp4", "Einführung in die Programmierung.mp4"},
+		{"Vorlesung Différentialgleichungen für Ingenieur·innen.mp4", "Vorlesung Différentialgleichungen für..."},
+		{"Ökonomie Übung Ärger über Öffnungszeiten und Größen.mp4", "Ökonomie Übung Ärger über Öffnungszei..."},
+		{"数据结构与算法第一讲数据结构与算法第一讲数据结构.mp4", "数据结构与算法第一讲数据结构与算法第..."},
+	}
+	for _, tt := range tests {
+		got := shortenName(tt.name, 40)
+		if got != tt.want {
+			t.Errorf("shortenName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if !utf8.ValidString(got) || runewidth.StringWidth(got) > 40 {
+			t.Errorf("shortenName(%q) = %q is not valid UTF-8 or wider than 40 columns", tt.name, got)
+		}
+	}
+}
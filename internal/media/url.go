@@ -0,0 +1,131 @@
+package media
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ResourceKind is the type of SwitchTube page a URL points to.
+type ResourceKind string
+
+const (
+	ResourceVideo   ResourceKind = "video"
+	ResourceChannel ResourceKind = "channel"
+)
+
+// Resource is a video or channel identified by a URL.
+type Resource struct {
+	Kind ResourceKind
+	ID   string
+}
+
+// resourcePaths are the path prefixes of SwitchTube URLs, followed by the ID.
+// The embed player and the API use the same IDs as the pages.
+var resourcePaths = []struct {
+	prefix []string
+	kind   ResourceKind
+}{
+	{[]string{"videos"}, ResourceVideo},
+	{[]string{"embed"}, ResourceVideo},
+	{[]string{"channels"}, ResourceChannel},
+	{[]string{"api", "v1", "browse", "videos"}, ResourceVideo},
+	{[]string{"api", "v1", "browse", "channels"}, ResourceChannel},
+}
+
+// ParseBaseURL checks the URL of a SwitchTube instance, e.g. a staging
+// instance or a mirror below a path, and returns it without trailing slash.
+func ParseBaseURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", raw, err)
+	}
+	shown := u.Redacted() // without password
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid base URL %q, must start with https:// or http://", shown)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q, the host is missing", shown)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid base URL %q, must not contain credentials, query or fragment", shown)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// isURL reports whether s looks like a URL rather than a bare ID.
+func isURL(s string) bool {
+	return strings.Contains(s, "/")
+}
+
+// ParseURL returns the video or channel a SwitchTube URL points to, e.g.
+// https://tube.switch.ch/videos/abc123, /channels/xyz or /embed/abc123.
+// The scheme and host may be left out. Query and fragment are ignored.
+// URLs of other hosts than tube.switch.ch and the client's instance are rejected.
+func (c *Client) ParseURL(raw string) (Resource, error) {
+	s := strings.TrimSpace(raw)
+	if !strings.Contains(s, "://") && !strings.HasPrefix(s, "/") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return Resource{}, fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if u.Host != "" && !c.isSwitchTubeHost(u.Host) {
+		return Resource{}, fmt.Errorf("%q is not a SwitchTube URL, the host %s is unknown", raw, u.Host)
+	}
+
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	for _, path := range resourcePaths {
+		n := len(path.prefix)
+		if len(segments) <= n || !hasPrefixFold(segments, path.prefix) {
+			continue
+		}
+		return Resource{Kind: path.kind, ID: segments[n]}, nil
+	}
+	return Resource{}, fmt.Errorf("%q is not a SwitchTube video or channel URL", raw)
+}
+
+// isSwitchTubeHost reports whether host is tube.switch.ch or the host of the
+// instance the client is configured for.
+func (c *Client) isSwitchTubeHost(host string) bool {
+	for _, baseURL := range []string{SwitchTubeBaseURL, c.BaseURL} {
+		if u, err := url.Parse(baseURL); err == nil && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixFold(segments, prefix []string) bool {
+	for i, p := range prefix {
+		if !strings.EqualFold(segments[i], p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseVideoID returns the ID of a video given as ID or URL.
+func (c *Client) ParseVideoID(s string) (string, error) {
+	return c.parseID(s, ResourceVideo)
+}
+
+// ParseChannelID returns the ID of a channel given as ID or URL.
+func (c *Client) ParseChannelID(s string) (string, error) {
+	return c.parseID(s, ResourceChannel)
+}
+
+func (c *Client) parseID(s string, kind ResourceKind) (string, error) {
+	if !isURL(s) {
+		return s, nil
+	}
+	resource, err := c.ParseURL(s)
+	if err != nil {
+		return "", err
+	}
+	if resource.Kind != kind {
+		return "", fmt.Errorf("%q is a %s URL, not a %s URL", s, resource.Kind, kind)
+	}
+	return resource.ID, nil
+}
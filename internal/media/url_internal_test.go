@@ -0,0 +1,69 @@
+package media
+
+import "testing"
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Resource
+		wantErr bool
+	}{
+		{"https://tube.switch.ch/videos/abc123", Resource{ResourceVideo, "abc123"}, false},
+		{"https://tube.switch.ch/videos/abc123/", Resource{ResourceVideo, "abc123"}, false},
+		{"https://tube.switch.ch/videos/abc123?t=42#player", Resource{ResourceVideo, "abc123"}, false},
+		{"https://TUBE.switch.ch/Videos/abc123", Resource{ResourceVideo, "abc123"}, false},
+		{"http://tube.switch.ch/embed/abc123", Resource{ResourceVideo, "abc123"}, false},
+		{"https://tube.switch.ch/channels/xyz", Resource{ResourceChannel, "xyz"}, false},
+		{"https://tube.switch.ch/api/v1/browse/videos/abc123/video_variants", Resource{ResourceVideo, "abc123"}, false},
+		{"https://tube.switch.ch/api/v1/browse/channels/xyz", Resource{ResourceChannel, "xyz"}, false},
+		{"tube.switch.ch/videos/abc123", Resource{ResourceVideo, "abc123"}, false},
+		{"/channels/xyz", Resource{ResourceChannel, "xyz"}, false},
+		{" https://tube.switch.ch/videos/abc123 ", Resource{ResourceVideo, "abc123"}, false},
+		{"https://staging.example.ch/videos/abc123", Resource{ResourceVideo, "abc123"}, false},
+		{"https://tube.switch.ch/videos", Resource{}, true},
+		{"https://tube.switch.ch/profiles/me", Resource{}, true},
+		{"https://tube.switch.ch/", Resource{}, true},
+		{"https://example.com/videos/abc123", Resource{}, true},
+		{"https://tube.switch.ch.example.com/videos/abc123", Resource{}, true},
+		{"https://staging.example.ch:8443/videos/abc123", Resource{}, true},
+		{"example.com/channels/xyz", Resource{}, true},
+		{"https://tube.switch.ch/videos/%zz", Resource{}, true},
+	}
+	c := NewClient("token")
+	c.BaseURL = "https://staging.example.ch/mirror"
+	for _, tt := range tests {
+		got, err := c.ParseURL(tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseURL(%q) = %v, %v, want %v, error %t", tt.url, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		parse     func(c *Client, s string) (string, error)
+		want      string
+		wantError bool
+	}{
+		{"video ID", "abc123", (*Client).ParseVideoID, "abc123", false},
+		{"video URL", "https://tube.switch.ch/videos/abc123", (*Client).ParseVideoID, "abc123", false},
+		{"embed URL", "https://tube.switch.ch/embed/abc123", (*Client).ParseVideoID, "abc123", false},
+		{"channel ID", "xyz", (*Client).ParseChannelID, "xyz", false},
+		{"channel URL", "/channels/xyz", (*Client).ParseChannelID, "xyz", false},
+		{"channel URL as video", "https://tube.switch.ch/channels/xyz", (*Client).ParseVideoID, "", true},
+		{"video URL as channel", "https://tube.switch.ch/videos/abc123", (*Client).ParseChannelID, "", true},
+		{"foreign video URL", "https://example.com/videos/abc123", (*Client).ParseVideoID, "", true},
+		{"foreign channel URL", "https://example.com/channels/xyz", (*Client).ParseChannelID, "", true},
+	}
+	c := NewClient("token")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(c, tt.input)
+			if (err != nil) != tt.wantError || got != tt.want {
+				t.Errorf("got %q, %v, want %q, error %t", got, err, tt.want, tt.wantError)
+			}
+		})
+	}
+}
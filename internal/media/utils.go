@@ -2,6 +2,10 @@ package media
 
 import (
 	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -9,11 +13,72 @@ const (
 	maxFilenameLength = 255
 )
 
-func ensureMp4Suffix(name string) string {
-	if !strings.HasSuffix(name, ".mp4") {
-		return name + ".mp4"
+// mediaTypeExtensions maps the media types of variants to file extensions.
+// Types missing here fall back to the system MIME database.
+var mediaTypeExtensions = map[string]string{
+	"video/mp4":        ".mp4",
+	"audio/mp4":        ".m4a",
+	"audio/x-m4a":      ".m4a",
+	"audio/aac":        ".aac",
+	"audio/mpeg":       ".mp3",
+	"video/webm":       ".webm",
+	"audio/webm":       ".weba",
+	"audio/ogg":        ".ogg",
+	"video/ogg":        ".ogv",
+	"video/quicktime":  ".mov",
+	"video/x-matroska": ".mkv",
+}
+
+// mp4MediaTypes are the media types stored in the MP4 container format,
+// which can be verified and tagged.
+var mp4MediaTypes = []string{"video/mp4", "audio/mp4", "audio/x-m4a"}
+
+// extensionForMediaType returns the file extension of a media type, ".mp4" if
+// the variant or its type is unknown.
+func extensionForMediaType(mediaType string) string {
+	mediaType = baseMediaType(mediaType)
+	if ext, ok := mediaTypeExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".mp4"
+}
+
+// isMP4MediaType reports whether files of the media type use the MP4 container.
+func isMP4MediaType(mediaType string) bool {
+	return slices.Contains(mp4MediaTypes, baseMediaType(mediaType))
+}
+
+// baseMediaType strips parameters like codecs from a media type and lowercases it.
+func baseMediaType(mediaType string) string {
+	base, _, _ := strings.Cut(mediaType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// ensureExtension appends ext to name unless it already ends with it. A known
+// media extension of another type is replaced, so "lecture.mp4" becomes "lecture.m4a".
+func ensureExtension(name, ext string) string {
+	current := filepath.Ext(name)
+	if strings.EqualFold(current, ext) {
+		return name
+	}
+	for _, known := range mediaTypeExtensions {
+		if strings.EqualFold(current, known) {
+			return strings.TrimSuffix(name, current) + ext
+		}
+	}
+	return name + ext
+}
+
+// fileSize returns the size of the file, or 0 if it can't be determined.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
 	}
-	return name
+	return info.Size()
 }
 
 func sanitizeFilename(name string) string {
@@ -29,28 +94,54 @@ func sanitizeFilename(name string) string {
 	return sanitized
 }
 
-// NOTE: from the api docs "Video variants are ordered on their quality level with the highest quality variant first."
-func selectBestVariant(variants []VideoVariant) *VideoVariant {
-	for _, variant := range variants {
-		if variant.MediaType == "video/mp4" {
-			return &variant
-		}
+func (c *Client) printDownloadSummary(summary *DownloadSummary) {
+	fmt.Fprintf(c.Out, "\nDownload Summary:\n")
+	fmt.Fprintf(c.Out, "Total videos: %d\n", summary.Total)
+	fmt.Fprintf(c.Out, "Successfully downloaded: %d\n", summary.Succeeded)
+	fmt.Fprintf(c.Out, "Failed: %d\n", summary.Failed)
+	if summary.Skipped > 0 {
+		fmt.Fprintf(c.Out, "Skipped (already downloaded): %d\n", summary.Skipped)
 	}
-	return nil
-}
-
-func printDownloadSummary(summary *DownloadSummary) {
-	fmt.Printf("\nDownload Summary:\n")
-	fmt.Printf("Total videos: %d\n", summary.Total)
-	fmt.Printf("Successfully downloaded: %d\n", summary.Succeeded)
-	fmt.Printf("Failed: %d\n", summary.Failed)
 
 	if summary.Failed > 0 {
-		fmt.Println("\nFailed downloads:")
+		fmt.Fprintln(c.Out, "\nFailed downloads:")
 		for _, result := range summary.Results {
-			if result.Error != nil {
-				fmt.Printf("- Video %s: %v\n", result.VideoID, result.Error)
+			if result.Error == nil {
+				continue
+			}
+			if result.VideoID == "" && result.ChannelID != "" {
+				fmt.Fprintf(c.Out, "- Channel %s: %v\n", result.ChannelID, result.Error)
+				continue
+			}
+			if result.Attempts > 1 {
+				fmt.Fprintf(c.Out, "- Video %s: %v (after %d attempts)\n", result.VideoID, result.Error, result.Attempts)
+			} else {
+				fmt.Fprintf(c.Out, "- Video %s: %v\n", result.VideoID, result.Error)
 			}
 		}
 	}
 }
+
+func (c *Client) PrintSyncReport(report *SyncReport) {
+	fmt.Fprintf(c.Out, "\nSync Report for '%s':\n", report.ChannelName)
+	c.printTitles("Added", report.Added)
+	if len(report.Skipped) > 0 {
+		c.printTitles("Skipped (already downloaded)", report.Skipped)
+	}
+	c.printTitles("Updated", report.Updated)
+	c.printTitles("Removed upstream", report.Removed)
+
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(c.Out, "Failed: %d\n", len(report.Failed))
+		for _, result := range report.Failed {
+			fmt.Fprintf(c.Out, "- Video %s: %v\n", result.VideoID, result.Error)
+		}
+	}
+}
+
+func (c *Client) printTitles(label string, titles []string) {
+	fmt.Fprintf(c.Out, "%s: %d\n", label, len(titles))
+	for _, title := range titles {
+		fmt.Fprintf(c.Out, "- %s\n", title)
+	}
+}
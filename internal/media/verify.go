@@ -0,0 +1,97 @@
+package media
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// minDurationTolerance allows for the API duration being "slightly different"
+	// from the media files, e.g. due to rounding or an extra frame.
+	minDurationTolerance = 2 * time.Second
+	durationTolerance    = 0.01 // relative tolerance for long videos
+)
+
+// VerifyMP4 checks the box structure of an MP4 file: ftyp, moov and mdat must
+// be present and all box sizes consistent. If expected is not 0, the movie
+// duration must match it within a tolerance. It returns the movie duration.
+func VerifyMP4(path string, expected time.Duration) (_ time.Duration, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", path, cerr)
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	boxes, err := readBoxes(file, 0, info.Size())
+	if err != nil {
+		return 0, err
+	}
+	for _, required := range []string{"ftyp", "moov", "mdat"} {
+		if _, ok := findBox(boxes, required); !ok {
+			return 0, fmt.Errorf("%w: no %s box", errInvalidMP4, required)
+		}
+	}
+	if err = checkBoxTree(file, boxes); err != nil {
+		return 0, err
+	}
+
+	duration, err := movieDuration(file, boxes)
+	if err != nil {
+		return 0, err
+	}
+	if err = checkDuration(duration, expected); err != nil {
+		return duration, err
+	}
+	return duration, nil
+}
+
+func movieDuration(file *os.File, boxes []mp4Box) (time.Duration, error) {
+	moov, _ := findBox(boxes, "moov")
+	children, err := readBoxes(file, moov.payloadOffset(), moov.end())
+	if err != nil {
+		return 0, err
+	}
+	mvhd, ok := findBox(children, "mvhd")
+	if !ok {
+		return 0, fmt.Errorf("%w: no mvhd box", errInvalidMP4)
+	}
+	data, err := readBoxPayload(file, mvhd)
+	if err != nil {
+		return 0, err
+	}
+	timescale, units, err := parseMovieHeader(data)
+	if err != nil {
+		return 0, err
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("%w: movie timescale is 0", errInvalidMP4)
+	}
+	seconds := units / uint64(timescale)
+	remainder := units % uint64(timescale)
+	return time.Duration(seconds)*time.Second + //nolint:gosec // durations of videos fit easily
+		time.Duration(remainder)*time.Second/time.Duration(timescale), nil //nolint:gosec // remainder < timescale
+}
+
+// checkDuration compares the movie duration with the one reported by the API.
+// Fragmented files may report 0 in the movie header, which is not checked.
+func checkDuration(actual, expected time.Duration) error {
+	if expected <= 0 || actual == 0 {
+		return nil
+	}
+	tolerance := max(minDurationTolerance, time.Duration(float64(expected)*durationTolerance))
+	if diff := actual - expected; diff > tolerance || diff < -tolerance {
+		return fmt.Errorf("%w: duration is %s, expected %s",
+			errInvalidMP4, actual.Round(time.Second), expected.Round(time.Second))
+	}
+	return nil
+}
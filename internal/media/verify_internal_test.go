@@ -0,0 +1,81 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// movieFile returns an MP4 file with the given movie duration in milliseconds.
+func movieFile(durationMs uint32) []byte {
+	return buildMP4(true, func(offsets []int64) []byte {
+		trak := trakBox("vide", box("stsz", u32s(0, 0, 1, 11)), chunkOffsetBox(false, offsets...))
+		return box("moov", mvhdBox(1000, durationMs), trak)
+	}, testChunks[0])
+}
+
+func TestVerifyMP4Structure(t *testing.T) {
+	valid := movieFile(60000)
+	moov := videoMoov(false, nil)(make([]int64, len(testChunks)))
+	mdat := box("mdat", testChunks...)
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"moov after mdat", bytes.Join([][]byte{ftypBox(), mdat, moov}, nil), false},
+		{"truncated", valid[:len(valid)-4], true},
+		{"truncated header", valid[:len(valid)-len(testChunks[0])-5], true},
+		{"missing moov", bytes.Join([][]byte{ftypBox(), mdat}, nil), true},
+		{"missing mdat", bytes.Join([][]byte{ftypBox(), moov}, nil), true},
+		{"missing ftyp", bytes.Join([][]byte{moov, mdat}, nil), true},
+		{"inconsistent child box", bytes.Replace(valid, mvhdBox(1000, 60000)[:8], append(u32s(5000), "mvhd"...), 1), true},
+		{"empty", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "video.mp4", tt.data)
+			_, err := VerifyMP4(path, 0)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errInvalidMP4) {
+				t.Errorf("error %v is not an invalid MP4 error", err)
+			}
+		})
+	}
+}
+
+func TestVerifyMP4Duration(t *testing.T) {
+	tests := []struct {
+		name       string
+		durationMs uint32
+		expected   time.Duration
+		wantErr    bool
+	}{
+		{"exact", 60000, time.Minute, false},
+		{"short video, just inside 2s", 62000, time.Minute, false},
+		{"short video, just outside 2s", 62001, time.Minute, true},
+		{"short video, shorter inside 2s", 58000, time.Minute, false},
+		{"short video, shorter outside 2s", 57999, time.Minute, true},
+		{"long video, just inside 1%", 1010000, 1000 * time.Second, false},
+		{"long video, just outside 1%", 1010001, 1000 * time.Second, true},
+		{"long video, shorter outside 1%", 989999, 1000 * time.Second, true},
+		{"unknown expected duration", 1000, 0, false},
+		{"no movie duration", 0, time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "video.mp4", movieFile(tt.durationMs))
+			duration, err := VerifyMP4(path, tt.expected)
+			if want := time.Duration(tt.durationMs) * time.Millisecond; duration != want {
+				t.Errorf("duration is %s, want %s", duration, want)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}